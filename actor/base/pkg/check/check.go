@@ -23,10 +23,15 @@ type CheckOutput struct {
 	Revisions []Revision `json:"revisions"`
 }
 
+// Revision is a revision to check on the registry.
+// Tag is the image tag to look up. When it differs from ResolvedRevision,
+// the image is regarded as existing only if it was built from ResolvedRevision.
 type Revision struct {
 	Registry         string                            `json:"registry"`
 	ResolvedRevision string                            `json:"resolved_revision"`
 	Revision         string                            `json:"revision"`
+	TagPolicy        buildv1beta1.ImageTagPolicyType   `json:"tag_policy,omitempty"`
+	Tag              string                            `json:"tag,omitempty"`
 	Exist            buildv1beta1.ImageConditionStatus `json:"exist"`
 }
 
@@ -90,6 +95,7 @@ func (c *Check) GetImage(ctx context.Context) (*buildv1beta1.Image, error) {
 	if err != nil {
 		return nil, err
 	}
+	image.Status.Conditions = imageutil.FillTarget(image.Status.Conditions, image.Spec.Targets)
 	c.image = image
 	return image, nil
 }
@@ -99,7 +105,12 @@ func (c *Check) GetInput(ctx context.Context) (*CheckInput, error) {
 	if err != nil {
 		return nil, err
 	}
-	conds := imageutil.GetConditionByStatus(image.Status.Conditions, buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageConditionStatusFalse)
+	conds := []buildv1beta1.ImageCondition{}
+	for _, cond := range imageutil.GetConditionByTarget(image.Status.Conditions, buildv1beta1.ImageConditionTypeChecked, c.opt.ImageTarget) {
+		if cond.Status == buildv1beta1.ImageConditionStatusFalse {
+			conds = append(conds, cond)
+		}
+	}
 	input := GetCheckInput(c.opt.ImageTarget, conds)
 	return &input, nil
 }
@@ -108,15 +119,23 @@ func (c *Check) UpdateImage(ctx context.Context, image *buildv1beta1.Image, outp
 	logrus.Info("==== output ====")
 	pp.Println(output)
 	for _, rev := range output.Revisions {
+		tagPolicy := rev.TagPolicy
+		if tagPolicy == "" {
+			tagPolicy = buildv1beta1.ImageTagPolicyTypeUnused
+		}
 		image.Status.Conditions = imageutil.UpdateCheckedCondition(
 			image.Status.Conditions,
 			buildv1beta1.ImageConditionStatusTrue,
+			tagPolicy,
+			c.opt.ImageTarget,
 			rev.Revision,
 			rev.ResolvedRevision,
 		)
 		image.Status.Conditions = imageutil.UpdateUploadedCondition(
 			image.Status.Conditions,
 			rev.Exist,
+			tagPolicy,
+			c.opt.ImageTarget,
 			rev.Revision,
 			rev.ResolvedRevision,
 		)
@@ -127,7 +146,13 @@ func (c *Check) UpdateImage(ctx context.Context, image *buildv1beta1.Image, outp
 func GetCheckInput(registry string, conds []buildv1beta1.ImageCondition) CheckInput {
 	prs := []Revision{}
 	for _, c := range conds {
-		prs = append(prs, Revision{Registry: registry, ResolvedRevision: c.ResolvedRevision, Revision: c.Revision})
+		prs = append(prs, Revision{
+			Registry:         registry,
+			ResolvedRevision: c.ResolvedRevision,
+			Revision:         c.Revision,
+			TagPolicy:        c.TagPolicy,
+			Tag:              imageutil.ImageTag(c),
+		})
 	}
 	return CheckInput{
 		Revisions: prs,
@@ -48,6 +48,63 @@ func TestGetCheckFile(t *testing.T) {
 						Registry:         "reg",
 						ResolvedRevision: "testrevhash",
 						Revision:         "master",
+						TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+						Tag:              "testrevhash",
+					},
+				},
+			},
+		},
+		{
+			name: "branch_name",
+			args: args{
+				registry: "reg",
+				conds: []buildv1beta1.ImageCondition{
+					{
+						LastTransitionTime: &now,
+						Type:               buildv1beta1.ImageConditionTypeChecked,
+						Status:             buildv1beta1.ImageConditionStatusFalse,
+						TagPolicy:          buildv1beta1.ImageTagPolicyTypeBranchName,
+						Revision:           "feature/test",
+						ResolvedRevision:   "testrevhash",
+					},
+				},
+			},
+			want: CheckInput{
+				Revisions: []Revision{
+					{
+						Registry:         "reg",
+						ResolvedRevision: "testrevhash",
+						Revision:         "feature/test",
+						TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchName,
+						Tag:              "feature-test",
+					},
+				},
+			},
+		},
+		{
+			name: "tag_template",
+			args: args{
+				registry: "reg",
+				conds: []buildv1beta1.ImageCondition{
+					{
+						LastTransitionTime: &now,
+						Type:               buildv1beta1.ImageConditionTypeChecked,
+						Status:             buildv1beta1.ImageConditionStatusFalse,
+						TagPolicy:          buildv1beta1.ImageTagPolicyTypeBranchHash,
+						Revision:           "master",
+						ResolvedRevision:   "testrevhash",
+						Tag:                "master-testrev-20220502",
+					},
+				},
+			},
+			want: CheckInput{
+				Revisions: []Revision{
+					{
+						Registry:         "reg",
+						ResolvedRevision: "testrevhash",
+						Revision:         "master",
+						TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+						Tag:              "master-testrev-20220502",
 					},
 				},
 			},
@@ -193,11 +250,13 @@ func TestCheck_UpdateImage(t *testing.T) {
 					ResolvedRevision: "resolved",
 					Revision:         "master",
 					TagPolicy:        buildv1beta1.ImageTagPolicyTypeUnused,
+					Target:           "target",
 				},
 				{
 					Type:             buildv1beta1.ImageConditionTypeUploaded,
 					Status:           buildv1beta1.ImageConditionStatusFalse,
 					TagPolicy:        buildv1beta1.ImageTagPolicyTypeUnused,
+					Target:           "target",
 					ResolvedRevision: "resolved",
 					Revision:         "master",
 				},
@@ -2,6 +2,9 @@ package detect
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -10,6 +13,7 @@ import (
 	"github.com/takutakahashi/oci-image-operator/actor/base/pkg/base"
 	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
 	imageutil "github.com/takutakahashi/oci-image-operator/pkg/image"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ktypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -68,50 +72,225 @@ func (d *Detect) UpdateImage(ctx context.Context, detectFile *DetectFile) (*buil
 	}
 
 	newImage := image.DeepCopy()
-	newConditions := ensureConditions(newImage.Status.Conditions, detectFile)
+	newConditions := imageutil.FillTarget(newImage.Status.Conditions, newImage.Spec.Targets)
+	newConditions = ensureConditions(newConditions, newImage.Spec.Targets, newImage.Spec.Repository, detectFile)
 	diff := cmp.Diff(image.Status.Conditions, newConditions, cmpopts.IgnoreFields(buildv1beta1.ImageCondition{}, "LastTransitionTime"))
 	logrus.Infof("diff: %s", diff)
-	if diff != "" {
-		newImage.Status.Conditions = newConditions
-		if err := d.c.Status().Update(ctx, newImage); err != nil {
-			return nil, err
-		}
-		logrus.Info("image updated")
+	now := metav1.Now()
+	if diff == "" && !recordPoll(image.Status.LastDetectTime, now.Time) {
+		return newImage, nil
+	}
+	newImage.Status.Conditions = newConditions
+	newImage.Status.LastDetectTime = &now
+	if err := d.c.Status().Update(ctx, newImage); err != nil {
+		return nil, err
 	}
 	logrus.Info("image ensured")
 	return newImage, nil
 }
 
+// recordPoll returns true if a poll without diff should be recorded. The controller finds the stale detector by LastDetectTime.
+func recordPoll(last *metav1.Time, now time.Time) bool {
+	return last == nil || now.Sub(last.Time) >= imageutil.DetectRecordInterval
+}
+
 // 1. cancel previous build
 // 2. add new check condition
-func ensureConditions(conditions []buildv1beta1.ImageCondition, detectFile *DetectFile) []buildv1beta1.ImageCondition {
+func ensureConditions(conditions []buildv1beta1.ImageCondition, targets []buildv1beta1.ImageTarget, repository buildv1beta1.ImageRepository, detectFile *DetectFile) []buildv1beta1.ImageCondition {
+	pp.Println(conditions)
+	policies := repository.TagPolicies
+	revs := resolveRevisions(policies, detectFile)
+	for _, target := range targets {
+		for _, rev := range revs {
+			conds := imageutil.GetCondition(conditions, buildv1beta1.ImageConditionTypeChecked)
+			checked := buildv1beta1.ImageConditionStatusFalse
+			for _, cond := range conds {
+				// failed checks are kept until the revision moves not to run the failed job again
+				if cond.ResolvedRevision == rev.resolvedRevision && cond.Target == target.Name && cond.TagPolicy == rev.policy &&
+					(cond.Status == buildv1beta1.ImageConditionStatusTrue || cond.Status == buildv1beta1.ImageConditionStatusFailed) {
+					checked = cond.Status
+				}
+			}
+			prev := imageutil.GetConditionBy(conditions, buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageCondition{TagPolicy: rev.policy, Revision: rev.revision, Target: target.Name})
+			conditions = imageutil.MarkUploadConditionAsCanceled(conditions, rev.policy, target.Name, rev.revision, rev.resolvedRevision)
+			conditions = imageutil.UpdateCondition(conditions, buildv1beta1.ImageConditionTypeChecked, &checked,
+				rev.policy, target.Name, rev.revision, rev.resolvedRevision)
+			// reason and message describe the check of the previous revision
+			if prev.ResolvedRevision != rev.resolvedRevision {
+				conditions = clearReason(conditions, target.Name, rev)
+			}
+			// render the tag only when the revision moves so that check and upload see the same tag
+			if tmpl := imageutil.TagTemplate(repository, target); tmpl != "" && (prev.Tag == "" || prev.ResolvedRevision != rev.resolvedRevision) {
+				conditions = renderTag(conditions, tmpl, target.Name, rev)
+			}
+		}
+	}
+	conditions = removeUnmatchedBranches(conditions, policies, revs)
+	conditions = removeSupersededVersions(conditions, revs)
+	pp.Println("-----------  before and after ------------")
 	pp.Println(conditions)
-	for branch, resolvedRevision := range detectFile.Branches {
+	return conditions
+}
+
+func clearReason(conditions []buildv1beta1.ImageCondition, target string, rev detectedRevision) []buildv1beta1.ImageCondition {
+	cond := imageutil.GetConditionBy(conditions, buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageCondition{TagPolicy: rev.policy, Revision: rev.revision, Target: target})
+	cond.Reason, cond.Message = "", ""
+	return imageutil.SetCondition(conditions, cond)
+}
+
+func renderTag(conditions []buildv1beta1.ImageCondition, tmpl, target string, rev detectedRevision) []buildv1beta1.ImageCondition {
+	cond := imageutil.GetConditionBy(conditions, buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageCondition{TagPolicy: rev.policy, Revision: rev.revision, Target: target})
+	tag, err := imageutil.RenderTag(tmpl, cond, time.Now())
+	if err != nil {
+		logrus.Warnf("failed to render tag template: %v", err)
+		return conditions
+	}
+	cond.Tag = tag
+	return imageutil.SetCondition(conditions, cond)
+}
+
+type detectedRevision struct {
+	policy           buildv1beta1.ImageTagPolicyType
+	revision         string
+	resolvedRevision string
+}
+
+// resolveRevisions maps detected branches and tags to tag policies.
+// semver policies and branch patterns record the matched tag or branch name as the revision.
+// branches and the latest tag which no policy refers to are recorded as branchHash and tagHash.
+func resolveRevisions(policies []buildv1beta1.ImageTagPolicy, detectFile *DetectFile) []detectedRevision {
+	ret := []detectedRevision{}
+	names := []string{}
+	for branch := range detectFile.Branches {
+		names = append(names, branch)
+	}
+	sort.Strings(names)
+	branches, tags := map[string]bool{}, map[string]bool{}
+	hasPattern := false
+	for _, policy := range policies {
+		resolvedRevision := ""
+		switch policy.Policy {
+		case buildv1beta1.ImageTagPolicyTypeBranchHash, buildv1beta1.ImageTagPolicyTypeBranchName:
+			if imageutil.IsBranchPattern(policy) {
+				hasPattern = true
+				for _, branch := range names {
+					ok, err := imageutil.MatchBranch(policy, branch)
+					if err != nil {
+						logrus.Warnf("invalid branch pattern: %v", err)
+						break
+					}
+					if !ok || detectFile.Branches[branch] == "" {
+						continue
+					}
+					branches[branch] = true
+					ret = append(ret, detectedRevision{policy: policy.Policy, revision: branch, resolvedRevision: detectFile.Branches[branch]})
+				}
+				continue
+			}
+			branches[policy.Revision] = true
+			resolvedRevision = detectFile.Branches[policy.Revision]
+		case buildv1beta1.ImageTagPolicyTypeTagHash, buildv1beta1.ImageTagPolicyTypeTagName:
+			tags[policy.Revision] = true
+			resolvedRevision = lookupTag(detectFile.Tags, policy.Revision)
+		case buildv1beta1.ImageTagPolicyTypeSemver:
+			matched, err := imageutil.MatchSemver(policy, detectFile.Tags)
+			if err != nil {
+				logrus.Warnf("invalid semver policy: %v", err)
+				continue
+			}
+			for _, tag := range matched {
+				tags[tag.Name] = true
+				ret = append(ret, detectedRevision{policy: policy.Policy, revision: tag.Name, resolvedRevision: tag.ResolvedRevision})
+			}
+			continue
+		}
 		if resolvedRevision == "" {
 			continue
+		}
+		ret = append(ret, detectedRevision{policy: policy.Policy, revision: policy.Revision, resolvedRevision: resolvedRevision})
+	}
+	for _, branch := range names {
+		// all branches of the repository are detected when patterns are used
+		if hasPattern || branches[branch] || detectFile.Branches[branch] == "" {
+			continue
+		}
+		ret = append(ret, detectedRevision{policy: buildv1beta1.ImageTagPolicyTypeBranchHash, revision: branch, resolvedRevision: detectFile.Branches[branch]})
+	}
+	if rev := lookupTag(detectFile.Tags, "latest"); !tags["latest"] && rev != "" {
+		ret = append(ret, detectedRevision{policy: buildv1beta1.ImageTagPolicyTypeTagHash, revision: "latest", resolvedRevision: rev})
+	}
+	return ret
+}
 
+// removeUnmatchedBranches removes conditions of branches which were added by branch patterns and no longer match.
+func removeUnmatchedBranches(conditions []buildv1beta1.ImageCondition, policies []buildv1beta1.ImageTagPolicy, revs []detectedRevision) []buildv1beta1.ImageCondition {
+	key := func(policy buildv1beta1.ImageTagPolicyType, revision string) string {
+		return fmt.Sprintf("%s/%s", policy, revision)
+	}
+	patterns, exact := map[buildv1beta1.ImageTagPolicyType]bool{}, map[string]bool{}
+	for _, policy := range policies {
+		if imageutil.IsBranchPattern(policy) {
+			patterns[policy.Policy] = true
+		} else {
+			exact[key(policy.Policy, policy.Revision)] = true
 		}
-		conds := imageutil.GetConditionByStatus(conditions, buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageConditionStatusTrue)
-		checked := buildv1beta1.ImageConditionStatusFalse
-		for _, cond := range conds {
-			if cond.ResolvedRevision == resolvedRevision {
-				checked = cond.Status
-			}
+	}
+	if len(patterns) == 0 {
+		return conditions
+	}
+	detected := map[string]bool{}
+	for _, rev := range revs {
+		detected[key(rev.policy, rev.revision)] = true
+	}
+	ret := []buildv1beta1.ImageCondition{}
+	for _, cond := range conditions {
+		k := key(cond.TagPolicy, cond.Revision)
+		if patterns[cond.TagPolicy] && !exact[k] && !detected[k] {
+			logrus.Infof("remove condition of unmatched branch: %s", k)
+			continue
 		}
-		conditions = imageutil.MarkUploadConditionAsCanceled(conditions, buildv1beta1.ImageTagPolicyTypeBranchHash, branch, resolvedRevision)
-		conditions = imageutil.UpdateCondition(conditions, buildv1beta1.ImageConditionTypeChecked, &checked,
-			buildv1beta1.ImageTagPolicyTypeBranchHash, branch, resolvedRevision)
+		ret = append(ret, cond)
 	}
-	for key, resolvedRevision := range detectFile.Tags {
-		if resolvedRevision == "" {
+	return ret
+}
+
+// removeSupersededVersions removes conditions of versions which no longer match semver policies,
+// such as the previous highest version, so that only the versions which won remain.
+// Checks and uploads in progress are canceled first so that the controller deletes their jobs, and removed on the next poll.
+func removeSupersededVersions(conditions []buildv1beta1.ImageCondition, revs []detectedRevision) []buildv1beta1.ImageCondition {
+	detected := map[string]bool{}
+	for _, rev := range revs {
+		if rev.policy == buildv1beta1.ImageTagPolicyTypeSemver {
+			detected[rev.revision] = true
+		}
+	}
+	ret := []buildv1beta1.ImageCondition{}
+	for _, cond := range conditions {
+		if cond.TagPolicy != buildv1beta1.ImageTagPolicyTypeSemver || detected[cond.Revision] {
+			ret = append(ret, cond)
 			continue
 		}
-		if key == MapKeyLatestTagName || key == MapKeyLatestTagHash {
-			conditions = imageutil.UpdateCondition(conditions, buildv1beta1.ImageConditionTypeChecked, &buildv1beta1.ImageConditionStatusFalse,
-				buildv1beta1.ImageTagPolicyTypeTagHash, "latest", resolvedRevision)
+		switch cond.Status {
+		case buildv1beta1.ImageConditionStatusFalse, buildv1beta1.ImageConditionStatusUnknown:
+			logrus.Infof("cancel condition of superseded version: %s", cond.Revision)
+			cond.Status = buildv1beta1.ImageConditionStatusCanceled
+			ret = append(ret, cond)
+		default:
+			logrus.Infof("remove condition of superseded version: %s", cond.Revision)
 		}
 	}
-	pp.Println("-----------  before and after ------------")
-	pp.Println(conditions)
-	return conditions
+	return ret
+}
+
+func lookupTag(tags map[string]string, name string) string {
+	if rev, ok := tags[name]; ok {
+		return rev
+	}
+	if name != "latest" {
+		return ""
+	}
+	if rev, ok := tags[MapKeyLatestTagHash]; ok {
+		return rev
+	}
+	return tags[MapKeyLatestTagName]
 }
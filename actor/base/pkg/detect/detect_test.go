@@ -2,6 +2,7 @@ package detect
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/takutakahashi/oci-image-operator/actor/base/pkg/internal/testutil"
 	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	imageutil "github.com/takutakahashi/oci-image-operator/pkg/image"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ktypes "k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -44,6 +47,7 @@ func TestDetect_UpdateImage(t *testing.T) {
 					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
 					Type:             buildv1beta1.ImageConditionTypeChecked,
 					Status:           buildv1beta1.ImageConditionStatusFalse,
+					Target:           "ghcr.io/takutakahashi/test",
 					Revision:         "master",
 					ResolvedRevision: "aaa",
 				},
@@ -51,6 +55,7 @@ func TestDetect_UpdateImage(t *testing.T) {
 					TagPolicy:        buildv1beta1.ImageTagPolicyTypeTagHash,
 					Type:             buildv1beta1.ImageConditionTypeChecked,
 					Status:           buildv1beta1.ImageConditionStatusFalse,
+					Target:           "ghcr.io/takutakahashi/test",
 					Revision:         "latest",
 					ResolvedRevision: "000011112222",
 				},
@@ -82,3 +87,388 @@ func TestDetect_UpdateImage(t *testing.T) {
 		})
 	}
 }
+
+func Test_resolveRevisions(t *testing.T) {
+	type args struct {
+		policies   []buildv1beta1.ImageTagPolicy
+		detectFile *DetectFile
+	}
+	tests := []struct {
+		name string
+		args args
+		want []detectedRevision
+	}{
+		{
+			name: "policies",
+			args: args{
+				policies: []buildv1beta1.ImageTagPolicy{
+					{Policy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "main"},
+					{Policy: buildv1beta1.ImageTagPolicyTypeBranchHash, Revision: "main"},
+					{Policy: buildv1beta1.ImageTagPolicyTypeTagName, Revision: "v1.2.3"},
+					{Policy: buildv1beta1.ImageTagPolicyTypeTagHash, Revision: "latest"},
+					{Policy: buildv1beta1.ImageTagPolicyTypeTagName, Revision: "notfound"},
+				},
+				detectFile: &DetectFile{
+					Branches: map[string]string{
+						"main": "aaa",
+					},
+					Tags: map[string]string{
+						"v1.2.3":            "bbb",
+						MapKeyLatestTagHash: "ccc",
+					},
+				},
+			},
+			want: []detectedRevision{
+				{policy: buildv1beta1.ImageTagPolicyTypeBranchName, revision: "main", resolvedRevision: "aaa"},
+				{policy: buildv1beta1.ImageTagPolicyTypeBranchHash, revision: "main", resolvedRevision: "aaa"},
+				{policy: buildv1beta1.ImageTagPolicyTypeTagName, revision: "v1.2.3", resolvedRevision: "bbb"},
+				{policy: buildv1beta1.ImageTagPolicyTypeTagHash, revision: "latest", resolvedRevision: "ccc"},
+			},
+		},
+		{
+			name: "semver",
+			args: args{
+				policies: []buildv1beta1.ImageTagPolicy{
+					{
+						Policy: buildv1beta1.ImageTagPolicyTypeSemver,
+						Semver: &buildv1beta1.ImageTagPolicySemver{Constraint: ">=1.4.0 <2.0.0"},
+					},
+				},
+				detectFile: &DetectFile{
+					Branches: map[string]string{},
+					Tags: map[string]string{
+						"v1.3.0": "aaa",
+						"v1.4.0": "bbb",
+						"v1.5.0": "ccc",
+						"v2.0.0": "ddd",
+					},
+				},
+			},
+			want: []detectedRevision{
+				{policy: buildv1beta1.ImageTagPolicyTypeSemver, revision: "v1.5.0", resolvedRevision: "ccc"},
+			},
+		},
+		{
+			name: "branch_pattern",
+			args: args{
+				policies: []buildv1beta1.ImageTagPolicy{
+					{Policy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/*", Match: buildv1beta1.ImageTagPolicyMatchGlob},
+					{Policy: buildv1beta1.ImageTagPolicyTypeBranchHash, Revision: "main"},
+				},
+				detectFile: &DetectFile{
+					Branches: map[string]string{
+						"main":         "aaa",
+						"release/0502": "bbb",
+						"release/0425": "ccc",
+						"feature/test": "ddd",
+					},
+				},
+			},
+			want: []detectedRevision{
+				{policy: buildv1beta1.ImageTagPolicyTypeBranchName, revision: "release/0425", resolvedRevision: "ccc"},
+				{policy: buildv1beta1.ImageTagPolicyTypeBranchName, revision: "release/0502", resolvedRevision: "bbb"},
+				{policy: buildv1beta1.ImageTagPolicyTypeBranchHash, revision: "main", resolvedRevision: "aaa"},
+			},
+		},
+		{
+			name: "without_policy",
+			args: args{
+				policies: []buildv1beta1.ImageTagPolicy{},
+				detectFile: &DetectFile{
+					Branches: map[string]string{
+						"master": "aaa",
+						"empty":  "",
+					},
+					Tags: map[string]string{
+						MapKeyLatestTagHash: "ccc",
+					},
+				},
+			},
+			want: []detectedRevision{
+				{policy: buildv1beta1.ImageTagPolicyTypeBranchHash, revision: "master", resolvedRevision: "aaa"},
+				{policy: buildv1beta1.ImageTagPolicyTypeTagHash, revision: "latest", resolvedRevision: "ccc"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveRevisions(tt.args.policies, tt.args.detectFile); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRevisions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_removeUnmatchedBranches(t *testing.T) {
+	policies := []buildv1beta1.ImageTagPolicy{
+		{Policy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/*", Match: buildv1beta1.ImageTagPolicyMatchGlob},
+		{Policy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "main"},
+	}
+	conditions := []buildv1beta1.ImageCondition{
+		{Type: buildv1beta1.ImageConditionTypeChecked, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/0502"},
+		{Type: buildv1beta1.ImageConditionTypeUploaded, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/0502"},
+		{Type: buildv1beta1.ImageConditionTypeChecked, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/0425"},
+		{Type: buildv1beta1.ImageConditionTypeUploaded, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/0425"},
+		{Type: buildv1beta1.ImageConditionTypeChecked, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "main"},
+		{Type: buildv1beta1.ImageConditionTypeChecked, TagPolicy: buildv1beta1.ImageTagPolicyTypeTagHash, Revision: "latest"},
+	}
+	revs := []detectedRevision{
+		{policy: buildv1beta1.ImageTagPolicyTypeBranchName, revision: "release/0502", resolvedRevision: "bbb"},
+	}
+	want := []buildv1beta1.ImageCondition{
+		{Type: buildv1beta1.ImageConditionTypeChecked, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/0502"},
+		{Type: buildv1beta1.ImageConditionTypeUploaded, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/0502"},
+		{Type: buildv1beta1.ImageConditionTypeChecked, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "main"},
+		{Type: buildv1beta1.ImageConditionTypeChecked, TagPolicy: buildv1beta1.ImageTagPolicyTypeTagHash, Revision: "latest"},
+	}
+	if got := removeUnmatchedBranches(conditions, policies, revs); !reflect.DeepEqual(got, want) {
+		t.Errorf("removeUnmatchedBranches() = %v, want %v", got, want)
+	}
+}
+
+func Test_recordPoll(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		last *metav1.Time
+		want bool
+	}{
+		{name: "first", want: true},
+		{name: "recent", last: &metav1.Time{Time: now.Add(-time.Minute)}, want: false},
+		{name: "old", last: &metav1.Time{Time: now.Add(-imageutil.DetectRecordInterval)}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordPoll(tt.last, now); got != tt.want {
+				t.Errorf("recordPoll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ensureConditions_SemverWinnerMoves(t *testing.T) {
+	targets := []buildv1beta1.ImageTarget{{Name: "ghcr.io/test/test"}}
+	repository := buildv1beta1.ImageRepository{
+		TagPolicies: []buildv1beta1.ImageTagPolicy{
+			{Policy: buildv1beta1.ImageTagPolicyTypeSemver, Semver: &buildv1beta1.ImageTagPolicySemver{Constraint: ">=1.4.0 <2.0.0"}},
+		},
+	}
+	cond := func(condType buildv1beta1.ImageConditionType, status buildv1beta1.ImageConditionStatus, revision, resolvedRevision string) buildv1beta1.ImageCondition {
+		return buildv1beta1.ImageCondition{
+			Type:             condType,
+			Status:           status,
+			TagPolicy:        buildv1beta1.ImageTagPolicyTypeSemver,
+			Target:           "ghcr.io/test/test",
+			Revision:         revision,
+			ResolvedRevision: resolvedRevision,
+		}
+	}
+	detectFile := &DetectFile{Tags: map[string]string{"v1.4.0": "aaa", "v1.5.0": "bbb"}}
+	tests := []struct {
+		name       string
+		conditions []buildv1beta1.ImageCondition
+		want       []buildv1beta1.ImageCondition
+	}{
+		{
+			name: "uploaded",
+			conditions: []buildv1beta1.ImageCondition{
+				cond(buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageConditionStatusTrue, "v1.4.0", "aaa"),
+				cond(buildv1beta1.ImageConditionTypeUploaded, buildv1beta1.ImageConditionStatusTrue, "v1.4.0", "aaa"),
+			},
+			want: []buildv1beta1.ImageCondition{
+				cond(buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageConditionStatusFalse, "v1.5.0", "bbb"),
+			},
+		},
+		{
+			name: "uploading",
+			conditions: []buildv1beta1.ImageCondition{
+				cond(buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageConditionStatusTrue, "v1.4.0", "aaa"),
+				cond(buildv1beta1.ImageConditionTypeUploaded, buildv1beta1.ImageConditionStatusFalse, "v1.4.0", "aaa"),
+			},
+			want: []buildv1beta1.ImageCondition{
+				cond(buildv1beta1.ImageConditionTypeUploaded, buildv1beta1.ImageConditionStatusCanceled, "v1.4.0", "aaa"),
+				cond(buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageConditionStatusFalse, "v1.5.0", "bbb"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ensureConditions(tt.conditions, targets, repository, detectFile)
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(buildv1beta1.ImageCondition{}, "LastTransitionTime")); diff != "" {
+				t.Errorf("ensureConditions() diff: %s", diff)
+			}
+			// the canceled upload is removed on the next poll
+			got = ensureConditions(got, targets, repository, detectFile)
+			if len(got) != 1 || got[0].Revision != "v1.5.0" {
+				t.Errorf("conditions after next poll = %v", got)
+			}
+		})
+	}
+}
+
+func Test_ensureConditions(t *testing.T) {
+	now := metav1.Now()
+	targets := []buildv1beta1.ImageTarget{
+		{Name: "ghcr.io/test/test"},
+		{Name: "ghcr.io/test/override", TagTemplate: "{{ .ShortSHA }}"},
+	}
+	repository := buildv1beta1.ImageRepository{
+		TagPolicies: []buildv1beta1.ImageTagPolicy{
+			{Policy: buildv1beta1.ImageTagPolicyTypeBranchHash, Revision: "main"},
+		},
+		TagTemplate: "{{ .Branch }}-{{ .ShortSHA }}",
+	}
+	tests := []struct {
+		name       string
+		conditions []buildv1beta1.ImageCondition
+		detectFile *DetectFile
+		want       []buildv1beta1.ImageCondition
+	}{
+		{
+			name:       "render",
+			conditions: []buildv1beta1.ImageCondition{},
+			detectFile: &DetectFile{Branches: map[string]string{"main": "0123456789"}},
+			want: []buildv1beta1.ImageCondition{
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusFalse,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/test",
+					Revision:         "main",
+					ResolvedRevision: "0123456789",
+					Tag:              "main-0123456",
+				},
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusFalse,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/override",
+					Revision:         "main",
+					ResolvedRevision: "0123456789",
+					Tag:              "0123456",
+				},
+			},
+		},
+		{
+			name: "keep_rendered_tag",
+			conditions: []buildv1beta1.ImageCondition{
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusTrue,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/test",
+					Revision:         "main",
+					ResolvedRevision: "0123456789",
+					Tag:              "rendered-before",
+				},
+			},
+			detectFile: &DetectFile{Branches: map[string]string{"main": "0123456789"}},
+			want: []buildv1beta1.ImageCondition{
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusTrue,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/test",
+					Revision:         "main",
+					ResolvedRevision: "0123456789",
+					Tag:              "rendered-before",
+				},
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusFalse,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/override",
+					Revision:         "main",
+					ResolvedRevision: "0123456789",
+					Tag:              "0123456",
+				},
+			},
+		},
+		{
+			name: "keep_failed",
+			conditions: []buildv1beta1.ImageCondition{
+				{
+					Type:               buildv1beta1.ImageConditionTypeChecked,
+					Status:             buildv1beta1.ImageConditionStatusFailed,
+					TagPolicy:          buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:             "ghcr.io/test/test",
+					Revision:           "main",
+					ResolvedRevision:   "0123456789",
+					Tag:                "main-0123456",
+					Reason:             "JobFailed",
+					Message:            "job failed",
+					LastTransitionTime: &now,
+				},
+			},
+			detectFile: &DetectFile{Branches: map[string]string{"main": "0123456789"}},
+			want: []buildv1beta1.ImageCondition{
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusFailed,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/test",
+					Revision:         "main",
+					ResolvedRevision: "0123456789",
+					Tag:              "main-0123456",
+					Reason:           "JobFailed",
+					Message:          "job failed",
+				},
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusFalse,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/override",
+					Revision:         "main",
+					ResolvedRevision: "0123456789",
+					Tag:              "0123456",
+				},
+			},
+		},
+		{
+			name: "check_moved_revision",
+			conditions: []buildv1beta1.ImageCondition{
+				{
+					Type:               buildv1beta1.ImageConditionTypeChecked,
+					Status:             buildv1beta1.ImageConditionStatusFailed,
+					TagPolicy:          buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:             "ghcr.io/test/test",
+					Revision:           "main",
+					ResolvedRevision:   "0123456789",
+					Tag:                "main-0123456",
+					Reason:             "JobFailed",
+					Message:            "job failed",
+					LastTransitionTime: &now,
+				},
+			},
+			detectFile: &DetectFile{Branches: map[string]string{"main": "abcdefghij"}},
+			want: []buildv1beta1.ImageCondition{
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusFalse,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/test",
+					Revision:         "main",
+					ResolvedRevision: "abcdefghij",
+					Tag:              "main-abcdefg",
+				},
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusFalse,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/override",
+					Revision:         "main",
+					ResolvedRevision: "abcdefghij",
+					Tag:              "abcdefg",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ensureConditions(tt.conditions, targets, repository, tt.detectFile)
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(buildv1beta1.ImageCondition{}, "LastTransitionTime")); diff != "" {
+				t.Errorf("ensureConditions() diff: %s", diff)
+			}
+		})
+	}
+}
@@ -18,16 +18,27 @@ import (
 
 type Input struct {
 	Builds []ImageBuild `json:"builds"`
+	// Targets are the names of all targets of the Image, actors which build all targets alike can tell the target by them.
+	Targets []string `json:"targets,omitempty"`
 }
 
 type Output struct {
 	Builds []ImageBuild `json:"builds"`
 }
 
+// ImageBuild is an image to build and push.
+// ResolvedRevision is the commit to build, Tag is the same as it unless the tag policy pushes a name.
+// Context, Dockerfile and BuildArgs are copied from ImageTarget.
 type ImageBuild struct {
-	Target    string                            `json:"target"`
-	Tag       string                            `json:"tag"`
-	Succeeded buildv1beta1.ImageConditionStatus `json:"succeeded,omitempty"`
+	Target           string                            `json:"target"`
+	Tag              string                            `json:"tag"`
+	ResolvedRevision string                            `json:"resolved_revision,omitempty"`
+	Context          string                            `json:"context,omitempty"`
+	Dockerfile       string                            `json:"dockerfile,omitempty"`
+	BuildArgs        map[string]string                 `json:"build_args,omitempty"`
+	Succeeded        buildv1beta1.ImageConditionStatus `json:"succeeded,omitempty"`
+	// Digest is the digest of the pushed image. optional.
+	Digest string `json:"digest,omitempty"`
 }
 
 type Opt struct {
@@ -69,6 +80,7 @@ func (u *Upload) GetImage(ctx context.Context) (*buildv1beta1.Image, error) {
 	if err != nil {
 		return nil, err
 	}
+	image.Status.Conditions = imageutil.FillTarget(image.Status.Conditions, image.Spec.Targets)
 	u.image = image
 	return image, nil
 }
@@ -123,7 +135,14 @@ func (u *Upload) GetInput(ctx context.Context) (*Input, error) {
 	if err != nil {
 		return nil, err
 	}
-	out := getInput(u.opt.ImageTarget, image.Status.Conditions)
+	target, ok := imageutil.GetTarget(image.Spec.Targets, u.opt.ImageTarget)
+	if !ok {
+		target = buildv1beta1.ImageTarget{Name: u.opt.ImageTarget}
+	}
+	out := getInput(target, image.Status.Conditions)
+	for _, t := range image.Spec.Targets {
+		out.Targets = append(out.Targets, t.Name)
+	}
 	return &out, nil
 }
 
@@ -158,11 +177,18 @@ func (u *Upload) Execute(ctx context.Context) error {
 	return nil
 }
 
-func getInput(target string, conditions []buildv1beta1.ImageCondition) Input {
+func getInput(target buildv1beta1.ImageTarget, conditions []buildv1beta1.ImageCondition) Input {
 	builds := []ImageBuild{}
 	for _, cond := range conditions {
-		if cond.Type == buildv1beta1.ImageConditionTypeUploaded && cond.Status != buildv1beta1.ImageConditionStatusTrue && cond.Status != buildv1beta1.ImageConditionStatusCanceled {
-			builds = append(builds, ImageBuild{Tag: cond.ResolvedRevision, Target: target})
+		if cond.Type == buildv1beta1.ImageConditionTypeUploaded && cond.Target == target.Name && pending(cond) {
+			builds = append(builds, ImageBuild{
+				Tag:              imageutil.ImageTag(cond),
+				ResolvedRevision: cond.ResolvedRevision,
+				Target:           target.Name,
+				Context:          target.Context,
+				Dockerfile:       target.Dockerfile,
+				BuildArgs:        target.BuildArgs,
+			})
 		}
 	}
 	logrus.Info("==== input ====")
@@ -170,6 +196,16 @@ func getInput(target string, conditions []buildv1beta1.ImageCondition) Input {
 	return Input{Builds: builds}
 }
 
+// pending returns true if the upload of the condition is not finished.
+// Failed uploads are not built again here, the operator retries them as a new attempt with the retry policy.
+func pending(cond buildv1beta1.ImageCondition) bool {
+	switch cond.Status {
+	case buildv1beta1.ImageConditionStatusTrue, buildv1beta1.ImageConditionStatusCanceled, buildv1beta1.ImageConditionStatusFailed:
+		return false
+	}
+	return true
+}
+
 func (u *Upload) Export(input *Input) error {
 	if u.in == nil {
 		w, err := os.Create(base.InWorkDir("input"))
@@ -198,29 +234,52 @@ func (u *Upload) UpdateImage(ctx context.Context, image *buildv1beta1.Image, out
 	pp.Println(output)
 	for _, build := range output.Builds {
 		exist := false
-		for _, c := range imageutil.GetCondition(image.Status.Conditions, buildv1beta1.ImageConditionTypeUploaded) {
-			if c.ResolvedRevision == build.Tag {
+		for _, c := range imageutil.GetConditionByTarget(image.Status.Conditions, buildv1beta1.ImageConditionTypeUploaded, u.opt.ImageTarget) {
+			if imageutil.ImageTag(c) == build.Tag && (build.ResolvedRevision == "" || c.ResolvedRevision == build.ResolvedRevision) {
+				exist = true
+				if c.Status == buildv1beta1.ImageConditionStatusFailed {
+					// failed uploads are only reset by the operator
+					continue
+				}
 				image.Status.Conditions = imageutil.UpdateUploadedCondition(
 					image.Status.Conditions,
 					build.Succeeded,
+					c.TagPolicy,
+					u.opt.ImageTarget,
 					c.Revision,
-					build.Tag,
+					c.ResolvedRevision,
 				)
-				exist = true
 			}
 		}
 		if !exist {
 			image.Status.Conditions = imageutil.UpdateUploadedCondition(
 				image.Status.Conditions,
 				build.Succeeded,
+				buildv1beta1.ImageTagPolicyTypeUnused,
+				u.opt.ImageTarget,
 				"",
 				build.Tag,
 			)
 		}
+		if build.Digest != "" {
+			setDigest(image.Status.Conditions, u.opt.ImageTarget, build)
+		}
 	}
 	return u.c.Status().Update(ctx, image, &client.UpdateOptions{})
 }
 
+func setDigest(conditions []buildv1beta1.ImageCondition, target string, build ImageBuild) {
+	for i, c := range conditions {
+		if c.Type != buildv1beta1.ImageConditionTypeUploaded || c.Target != target || imageutil.ImageTag(c) != build.Tag {
+			continue
+		}
+		if build.ResolvedRevision != "" && c.ResolvedRevision != build.ResolvedRevision {
+			continue
+		}
+		conditions[i].Digest = build.Digest
+	}
+}
+
 func (u *Upload) Stop() {
 	if u.ch != nil {
 		u.ch <- true
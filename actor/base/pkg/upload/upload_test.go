@@ -17,13 +17,14 @@ import (
 	"github.com/takutakahashi/oci-image-operator/actor/base/pkg/base"
 	"github.com/takutakahashi/oci-image-operator/actor/base/pkg/internal/testutil"
 	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	imageutil "github.com/takutakahashi/oci-image-operator/pkg/image"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func Test_getInput(t *testing.T) {
 	type args struct {
-		target     string
+		target     buildv1beta1.ImageTarget
 		conditions []buildv1beta1.ImageCondition
 	}
 	tests := []struct {
@@ -34,39 +35,122 @@ func Test_getInput(t *testing.T) {
 		{
 			name: "ok",
 			args: args{
-				target: "target",
+				target: buildv1beta1.ImageTarget{Name: "target"},
 				conditions: []buildv1beta1.ImageCondition{
 					{
 						Type:             buildv1beta1.ImageConditionTypeUploaded,
 						Status:           buildv1beta1.ImageConditionStatusFalse,
+						Target:           "target",
 						ResolvedRevision: "resolved",
 					},
 					{
 						Type:             buildv1beta1.ImageConditionTypeUploaded,
 						Status:           buildv1beta1.ImageConditionStatusTrue,
+						Target:           "target",
 						ResolvedRevision: "resolved_true",
 					},
 					{
 						Type:             buildv1beta1.ImageConditionTypeUploaded,
 						Status:           buildv1beta1.ImageConditionStatusUnknown,
+						Target:           "target",
 						ResolvedRevision: "resolved_unknown",
 					},
 					{
 						Type:             buildv1beta1.ImageConditionTypeChecked,
 						Status:           buildv1beta1.ImageConditionStatusFalse,
+						Target:           "target",
 						ResolvedRevision: "resolved_checked",
 					},
+					{
+						Type:             buildv1beta1.ImageConditionTypeUploaded,
+						Status:           buildv1beta1.ImageConditionStatusFalse,
+						Target:           "another_target",
+						ResolvedRevision: "resolved_another_target",
+					},
 				},
 			},
 			want: Input{
 				Builds: []ImageBuild{
 					{
-						Target: "target",
-						Tag:    "resolved",
+						Target:           "target",
+						Tag:              "resolved",
+						ResolvedRevision: "resolved",
+					},
+					{
+						Target:           "target",
+						Tag:              "resolved_unknown",
+						ResolvedRevision: "resolved_unknown",
+					},
+				},
+			},
+		},
+		{
+			name: "monorepo",
+			args: args{
+				target: buildv1beta1.ImageTarget{
+					Name:       "target",
+					Context:    "services/api",
+					Dockerfile: "services/api/Dockerfile",
+					BuildArgs:  map[string]string{"GO_VERSION": "1.18"},
+				},
+				conditions: []buildv1beta1.ImageCondition{
+					{
+						Type:             buildv1beta1.ImageConditionTypeUploaded,
+						Status:           buildv1beta1.ImageConditionStatusFalse,
+						Target:           "target",
+						ResolvedRevision: "resolved",
 					},
+				},
+			},
+			want: Input{
+				Builds: []ImageBuild{
+					{
+						Target:           "target",
+						Tag:              "resolved",
+						ResolvedRevision: "resolved",
+						Context:          "services/api",
+						Dockerfile:       "services/api/Dockerfile",
+						BuildArgs:        map[string]string{"GO_VERSION": "1.18"},
+					},
+				},
+			},
+		},
+		{
+			name: "failed",
+			args: args{
+				target: buildv1beta1.ImageTarget{Name: "target"},
+				conditions: []buildv1beta1.ImageCondition{
+					{
+						Type:             buildv1beta1.ImageConditionTypeUploaded,
+						Status:           buildv1beta1.ImageConditionStatusFailed,
+						Target:           "target",
+						ResolvedRevision: "resolved_limit_exceeded",
+						Attempts:         3,
+						Reason:           imageutil.ReasonRetryLimitExceeded,
+					},
+					{
+						Type:             buildv1beta1.ImageConditionTypeUploaded,
+						Status:           buildv1beta1.ImageConditionStatusFailed,
+						Target:           "target",
+						ResolvedRevision: "resolved_backoff",
+						Reason:           imageutil.ReasonJobFailed,
+					},
+					{
+						Type:             buildv1beta1.ImageConditionTypeUploaded,
+						Status:           buildv1beta1.ImageConditionStatusFalse,
+						Target:           "target",
+						ResolvedRevision: "resolved_retrying",
+						Attempts:         2,
+						Reason:           imageutil.ReasonRetrying,
+					},
+				},
+			},
+			want: Input{
+				Builds: []ImageBuild{
 					{
-						Target: "target",
-						Tag:    "resolved_unknown",
+						Target:           "target",
+						Tag:              "resolved_retrying",
+						ResolvedRevision: "resolved_retrying",
 					},
 				},
 			},
@@ -322,6 +406,7 @@ func TestUpload_Execute(t *testing.T) {
 					Status:           buildv1beta1.ImageConditionStatusTrue,
 					ResolvedRevision: "test",
 					TagPolicy:        buildv1beta1.ImageTagPolicyTypeUnused,
+					Target:           "target",
 				},
 			},
 		},
@@ -407,6 +492,7 @@ func TestUpload_Run(t *testing.T) {
 					Status:           buildv1beta1.ImageConditionStatusTrue,
 					ResolvedRevision: "test",
 					TagPolicy:        buildv1beta1.ImageTagPolicyTypeUnused,
+					Target:           "target",
 				},
 			},
 		},
@@ -2,6 +2,9 @@ package upload
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,7 +43,7 @@ func (u Upload) Output(ctx context.Context, input *upload.Input) (upload.Output,
 		wg.Add(1)
 		go func(b upload.ImageBuild) {
 			err := retry.Do(func() error {
-				return u.gh.Dispatch(ctx, b.Tag, true)
+				return u.gh.Dispatch(ctx, workflowInputs(b, len(input.Targets) > 1), true)
 			}, retry.Delay(1*time.Minute), retry.Attempts(3))
 			if err != nil {
 				b.Succeeded = v1beta1.ImageConditionStatusFailed
@@ -58,3 +61,40 @@ func (u Upload) Output(ctx context.Context, input *upload.Input) (upload.Output,
 	wg.Wait()
 	return out, nil
 }
+
+// workflowInputs builds inputs of workflow_dispatch.
+// Optional inputs are passed only when they are set so that existing workflows keep working.
+// build_args is newline-separated KEY=VALUE as docker/build-push-action accepts.
+// target is passed only when the image has multiple targets, since the same workflow is dispatched for each of them.
+// Workflows must label the image with org.opencontainers.image.revision set to the revision input,
+// the registry check builds tags named after a branch or a tag again unless the label matches.
+func workflowInputs(b upload.ImageBuild, multiTarget bool) map[string]interface{} {
+	revision := b.ResolvedRevision
+	if revision == "" {
+		revision = b.Tag
+	}
+	inputs := map[string]interface{}{
+		"revision": revision,
+	}
+	if b.Tag != revision {
+		inputs["tag"] = b.Tag
+	}
+	if multiTarget {
+		inputs["target"] = b.Target
+	}
+	if b.Context != "" {
+		inputs["context"] = b.Context
+	}
+	if b.Dockerfile != "" {
+		inputs["dockerfile"] = b.Dockerfile
+	}
+	if len(b.BuildArgs) > 0 {
+		args := []string{}
+		for k, v := range b.BuildArgs {
+			args = append(args, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(args)
+		inputs["build_args"] = strings.Join(args, "\n")
+	}
+	return inputs
+}
@@ -76,3 +76,58 @@ func TestUpload_Output(t *testing.T) {
 		})
 	}
 }
+
+func Test_workflowInputs(t *testing.T) {
+	tests := []struct {
+		name        string
+		build       upload.ImageBuild
+		multiTarget bool
+		want        map[string]interface{}
+	}{
+		{
+			name:  "hash",
+			build: upload.ImageBuild{Target: "ghcr.io/test/test", Tag: "abc123", ResolvedRevision: "abc123"},
+			want:  map[string]interface{}{"revision": "abc123"},
+		},
+		{
+			name:  "name",
+			build: upload.ImageBuild{Target: "ghcr.io/test/test", Tag: "main", ResolvedRevision: "abc123"},
+			want:  map[string]interface{}{"revision": "abc123", "tag": "main"},
+		},
+		{
+			name: "monorepo",
+			build: upload.ImageBuild{
+				Target:           "ghcr.io/test/api",
+				Tag:              "abc123",
+				ResolvedRevision: "abc123",
+				Context:          "services/api",
+				Dockerfile:       "services/api/Dockerfile",
+				BuildArgs:        map[string]string{"B": "2", "A": "1"},
+			},
+			want: map[string]interface{}{
+				"revision":   "abc123",
+				"context":    "services/api",
+				"dockerfile": "services/api/Dockerfile",
+				"build_args": "A=1\nB=2",
+			},
+		},
+		{
+			name:        "multi_target",
+			build:       upload.ImageBuild{Target: "docker.io/test/test", Tag: "main", ResolvedRevision: "abc123"},
+			multiTarget: true,
+			want:        map[string]interface{}{"revision": "abc123", "tag": "main", "target": "docker.io/test/test"},
+		},
+		{
+			name:  "without_resolved_revision",
+			build: upload.ImageBuild{Target: "ghcr.io/test/test", Tag: "abc123"},
+			want:  map[string]interface{}{"revision": "abc123"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workflowInputs(tt.build, tt.multiTarget); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("workflowInputs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
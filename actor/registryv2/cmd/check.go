@@ -34,12 +34,13 @@ to quickly create a Cobra application.`,
 		if err != nil {
 			logrus.Fatal(err)
 		}
+		auth, err := registryAuth(os.Getenv("REGISTRY_IMAGE_NAME"))
+		if err != nil {
+			logrus.Fatal(err)
+		}
 		r, err := registryv2.Init(nil, registryv2.Opt{
 			Image: os.Getenv("REGISTRY_IMAGE_NAME"),
-			Auth: &registryv2.Auth{
-				Username: os.Getenv("REGISTRY_AUTH_USERNAME"),
-				Password: os.Getenv("REGISTRY_AUTH_PASSWORD"),
-			},
+			Auth:  auth,
 		})
 		if err != nil {
 			logrus.Fatal(err)
@@ -71,6 +72,18 @@ to quickly create a Cobra application.`,
 	},
 }
 
+// registryAuth returns the auth from REGISTRY_AUTH_DOCKERCONFIGJSON, REGISTRY_AUTH_TOKEN or REGISTRY_AUTH_USERNAME and PASSWORD.
+func registryAuth(image string) (*registryv2.Auth, error) {
+	if config := os.Getenv("REGISTRY_AUTH_DOCKERCONFIGJSON"); config != "" {
+		return registryv2.AuthFromDockerConfig([]byte(config), image)
+	}
+	return &registryv2.Auth{
+		Username: os.Getenv("REGISTRY_AUTH_USERNAME"),
+		Password: os.Getenv("REGISTRY_AUTH_PASSWORD"),
+		Token:    os.Getenv("REGISTRY_AUTH_TOKEN"),
+	}, nil
+}
+
 func init() {
 	rootCmd.AddCommand(checkCmd)
 
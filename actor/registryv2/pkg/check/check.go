@@ -49,7 +49,7 @@ func (c Check) Execute(ctx context.Context) error {
 func (c Check) Output(in *check.CheckInput) (check.CheckOutput, error) {
 	revs := []check.Revision{}
 	for _, rev := range in.Revisions {
-		exist, err := c.r.TagExists(rev.ResolvedRevision)
+		exist, err := c.exists(rev)
 		if err != nil {
 			logrus.Error(err)
 			exist = false
@@ -60,6 +60,25 @@ func (c Check) Output(in *check.CheckInput) (check.CheckOutput, error) {
 	return check.CheckOutput{Revisions: revs}, nil
 }
 
+// exists checks the tag points to the image built from the resolved revision.
+// tags named after the commit itself are immutable, so existence is enough.
+// Other tags move to new commits, so they are compared by the org.opencontainers.image.revision label.
+// A tag without the label can't be compared and is built again.
+func (c Check) exists(rev check.Revision) (bool, error) {
+	if rev.Tag == "" || rev.Tag == rev.ResolvedRevision {
+		return c.r.TagExists(rev.ResolvedRevision)
+	}
+	revision, err := c.r.Revision(rev.Tag)
+	if err != nil {
+		return false, err
+	}
+	if revision == "" {
+		logrus.Warnf("tag %s is not found or doesn't have the revision label, build it from %s", rev.Tag, rev.ResolvedRevision)
+		return false, nil
+	}
+	return revision == rev.ResolvedRevision, nil
+}
+
 func parseExist(b bool) v1beta1.ImageConditionStatus {
 	if b {
 		return v1beta1.ImageConditionStatusTrue
@@ -0,0 +1,71 @@
+package check
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/oci-image-operator/actor/base/pkg/check"
+	"github.com/takutakahashi/oci-image-operator/actor/registryv2/pkg/registryv2"
+)
+
+func TestCheck_exists(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/test/image/manifests/main", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"config":{"digest":"sha256:labeled"}}`))
+	})
+	mux.HandleFunc("/v2/test/image/blobs/sha256:labeled", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"config":{"Labels":{"org.opencontainers.image.revision":"abc123"}}}`))
+	})
+	mux.HandleFunc("/v2/test/image/manifests/unlabeled", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"config":{"digest":"sha256:unlabeled"}}`))
+	})
+	mux.HandleFunc("/v2/test/image/blobs/sha256:unlabeled", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"config":{}}`))
+	})
+	s := httptest.NewTLSServer(mux)
+	defer s.Close()
+	r, err := registryv2.Init(s.Client(), registryv2.Opt{Image: fmt.Sprintf("%s/test/image", strings.TrimPrefix(s.URL, "https://"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		rev  check.Revision
+		want bool
+	}{
+		{
+			name: "same_revision",
+			rev:  check.Revision{Tag: "main", ResolvedRevision: "abc123"},
+			want: true,
+		},
+		{
+			name: "moved",
+			rev:  check.Revision{Tag: "main", ResolvedRevision: "def456"},
+			want: false,
+		},
+		{
+			name: "without_label",
+			rev:  check.Revision{Tag: "unlabeled", ResolvedRevision: "def456"},
+			want: false,
+		},
+		{
+			name: "not_found",
+			rev:  check.Revision{Tag: "notfound", ResolvedRevision: "def456"},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Check{r: r}.exists(tt.rev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("exists() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package registryv2
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -12,10 +13,34 @@ import (
 	"github.com/takutakahashi/oci-image-operator/actor/base/pkg/external"
 )
 
+const revisionLabel = "org.opencontainers.image.revision"
+
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
 type token struct {
 	Val string `json:"token"`
 }
 
+type descriptor struct {
+	Digest string `json:"digest"`
+}
+
+type manifest struct {
+	Config    descriptor   `json:"config"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+type imageConfig struct {
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
 type Opt struct {
 	Image string
 	Auth  *Auth
@@ -23,7 +48,73 @@ type Opt struct {
 type Auth struct {
 	Username string
 	Password string
+	// Token is a bearer token. It takes precedence over Username and Password.
+	Token string
+}
+
+type dockerConfig struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	Auth          string `json:"auth"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+// dockerHubHosts are the keys of docker hub in docker config.
+var dockerHubHosts = []string{"docker.io", "index.docker.io", "registry-1.docker.io"}
+
+// AuthFromDockerConfig returns the auth of the entry for the registry host of the image in docker config json.
+func AuthFromDockerConfig(config []byte, image string) (*Auth, error) {
+	hostname, _, err := external.ParseImageName(image)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse image")
+	}
+	c := dockerConfig{}
+	if err := json.Unmarshal(config, &c); err != nil {
+		return nil, errors.Wrap(err, "failed to decode docker config")
+	}
+	for key, entry := range c.Auths {
+		if !matchHost(key, hostname) {
+			continue
+		}
+		auth := &Auth{Username: entry.Username, Password: entry.Password, Token: entry.RegistryToken}
+		if entry.Auth != "" && auth.Username == "" {
+			b, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to decode auth of %s", key)
+			}
+			username, password, ok := strings.Cut(string(b), ":")
+			if !ok {
+				return nil, fmt.Errorf("auth of %s is not username:password", key)
+			}
+			auth.Username, auth.Password = username, password
+		}
+		return auth, nil
+	}
+	return nil, fmt.Errorf("auth for %s is not found in docker config", hostname)
+}
+
+// matchHost returns true if the key of docker config is for the host. keys can be URLs like https://index.docker.io/v1/.
+func matchHost(key, hostname string) bool {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	key, _, _ = strings.Cut(key, "/")
+	if key == hostname {
+		return true
+	}
+	isDockerHub := func(h string) bool {
+		for _, d := range dockerHubHosts {
+			if h == d {
+				return true
+			}
+		}
+		return false
+	}
+	return isDockerHub(key) && isDockerHub(hostname)
 }
+
 type Registry struct {
 	c         *http.Client
 	opt       Opt
@@ -50,12 +141,68 @@ func (r Registry) TagExists(tag string) (bool, error) {
 	return err == nil && res.StatusCode == http.StatusOK, err
 }
 
-func (r Registry) get(url string) (*http.Response, error) {
+// Revision returns the revision label of the image config which the tag points to.
+// It returns empty string when the tag is not found.
+func (r Registry) Revision(tag string) (string, error) {
+	hostname, familiarName, err := external.ParseImageName(r.opt.Image)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse image")
+	}
+	m, found, err := r.getManifest(fmt.Sprintf("https://%s/v2/%s/manifests/%s", hostname, familiarName, tag))
+	if err != nil || !found {
+		return "", err
+	}
+	if len(m.Manifests) != 0 {
+		m, found, err = r.getManifest(fmt.Sprintf("https://%s/v2/%s/manifests/%s", hostname, familiarName, m.Manifests[0].Digest))
+		if err != nil || !found {
+			return "", err
+		}
+	}
+	res, err := r.get(fmt.Sprintf("https://%s/v2/%s/blobs/%s", hostname, familiarName, m.Config.Digest))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get image config: %s", res.Status)
+	}
+	c := imageConfig{}
+	if err := json.NewDecoder(res.Body).Decode(&c); err != nil {
+		return "", errors.Wrap(err, "failed to decode image config")
+	}
+	return c.Config.Labels[revisionLabel], nil
+}
+
+func (r Registry) getManifest(url string) (manifest, bool, error) {
+	m := manifest{}
+	res, err := r.get(url, manifestMediaTypes...)
+	if err != nil {
+		return m, false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return m, false, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return m, false, fmt.Errorf("failed to get manifest: %s", res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+		return m, false, errors.Wrap(err, "failed to decode manifest")
+	}
+	return m, true, nil
+}
+
+func (r Registry) get(url string, accept ...string) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	if isGhcr(url) {
+	for _, a := range accept {
+		req.Header.Add("Accept", a)
+	}
+	if r.opt.Auth != nil && r.opt.Auth.Token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", r.opt.Auth.Token))
+	} else if isGhcr(url) {
 		token, err := r.genTokenForGhcr()
 		if err != nil {
 			return nil, err
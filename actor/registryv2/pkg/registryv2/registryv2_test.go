@@ -1,8 +1,11 @@
 package registryv2
 
 import (
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -78,3 +81,128 @@ func TestRegistry_TagExists(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistry_Revision(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/test/image/manifests/main", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"manifests":[{"digest":"sha256:amd64"}]}`))
+	})
+	mux.HandleFunc("/v2/test/image/manifests/sha256:amd64", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"config":{"digest":"sha256:config"}}`))
+	})
+	mux.HandleFunc("/v2/test/image/blobs/sha256:config", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"config":{"Labels":{"org.opencontainers.image.revision":"abc123"}}}`))
+	})
+	s := httptest.NewTLSServer(mux)
+	defer s.Close()
+	type args struct {
+		tag string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			args: args{
+				tag: "main",
+			},
+			want: "abc123",
+		},
+		{
+			name: "not_found",
+			args: args{
+				tag: "notfound",
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Registry{
+				c: s.Client(),
+				opt: Opt{
+					Image: fmt.Sprintf("%s/test/image", strings.TrimPrefix(s.URL, "https://")),
+				},
+			}
+			got, err := r.Revision(tt.args.tag)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Registry.Revision() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Registry.Revision() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthFromDockerConfig(t *testing.T) {
+	config := []byte(`{"auths":{
+		"ghcr.io":{"username":"user","password":"pass"},
+		"https://index.docker.io/v1/":{"auth":"aHViOnNlY3JldA=="},
+		"registry.example.com":{"registrytoken":"token"}
+	}}`)
+	tests := []struct {
+		name    string
+		image   string
+		want    Auth
+		wantErr bool
+	}{
+		{
+			name:  "username_password",
+			image: "ghcr.io/takutakahashi/test",
+			want:  Auth{Username: "user", Password: "pass"},
+		},
+		{
+			name:  "docker_hub",
+			image: "takutakahashi/test",
+			want:  Auth{Username: "hub", Password: "secret"},
+		},
+		{
+			name:  "token",
+			image: "registry.example.com/test",
+			want:  Auth{Token: "token"},
+		},
+		{
+			name:    "not_found",
+			image:   "quay.io/takutakahashi/test",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AuthFromDockerConfig(config, tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AuthFromDockerConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("AuthFromDockerConfig() = %v, want %v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_BearerToken(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/test/image/manifests/main", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	s := httptest.NewTLSServer(mux)
+	defer s.Close()
+	r := Registry{
+		c: s.Client(),
+		opt: Opt{
+			Image: fmt.Sprintf("%s/test/image", strings.TrimPrefix(s.URL, "https://")),
+			Auth:  &Auth{Token: "token"},
+		},
+	}
+	got, err := r.TagExists("main")
+	if err != nil || !got {
+		t.Errorf("Registry.TagExists() = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConvertTo converts this Image to the Hub version (v1beta1).
+// Ready, Detecting and Building are not stored since they are derived from revisions.
+// Other conditions keep their order and are stored before revisions, which is the order the operator keeps in v1beta1.
+func (src *Image) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.Image)
+	dst.ObjectMeta = src.ObjectMeta
+	if err := convertSpec(&src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	dst.Status.Conditions = make([]v1beta1.ImageCondition, 0, len(src.Status.Revisions))
+	for _, cond := range src.Status.Conditions {
+		if derivedCondition(cond.Type) {
+			continue
+		}
+		c := v1beta1.ImageCondition{
+			Type:    v1beta1.ImageConditionType(cond.Type),
+			Status:  v1beta1.ImageConditionStatus(cond.Status),
+			Reason:  cond.Reason,
+			Message: cond.Message,
+		}
+		if !cond.LastTransitionTime.IsZero() {
+			t := cond.LastTransitionTime
+			c.LastTransitionTime = &t
+		}
+		dst.Status.Conditions = append(dst.Status.Conditions, c)
+	}
+	for _, rev := range src.Status.Revisions {
+		dst.Status.Conditions = append(dst.Status.Conditions, v1beta1.ImageCondition{
+			LastTransitionTime: rev.LastTransitionTime,
+			Type:               v1beta1.ImageConditionType(rev.Phase),
+			Status:             v1beta1.ImageConditionStatus(rev.Status),
+			Revision:           rev.Revision,
+			ResolvedRevision:   rev.ResolvedRevision,
+			TagPolicy:          v1beta1.ImageTagPolicyType(rev.TagPolicy),
+			Target:             rev.Target,
+			Tag:                rev.Tag,
+			Digest:             rev.Digest,
+			Reason:             rev.Reason,
+			Message:            rev.Message,
+			Attempts:           rev.Attempts,
+		})
+	}
+	if len(dst.Status.Conditions) == 0 {
+		dst.Status.Conditions = nil
+	}
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	dst.Status.Phase = v1beta1.ImagePhase(src.Status.Phase)
+	dst.Status.LastDetectTime = src.Status.LastDetectTime
+	dst.Status.LatestTags = src.Status.LatestTags
+	dst.Status.Latest = nil
+	if src.Status.Latest != nil {
+		if err := convertSpec(&src.Status.Latest, &dst.Status.Latest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *Image) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.Image)
+	dst.ObjectMeta = src.ObjectMeta
+	if err := convertSpec(&src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	dst.Status.Revisions = nil
+	others := []metav1.Condition{}
+	for _, cond := range src.Status.Conditions {
+		if !cond.Type.IsRevision() {
+			c := metav1.Condition{
+				Type:               string(cond.Type),
+				Status:             metav1.ConditionStatus(cond.Status),
+				ObservedGeneration: src.Generation,
+				Reason:             cond.Reason,
+				Message:            cond.Message,
+			}
+			if cond.LastTransitionTime != nil {
+				c.LastTransitionTime = *cond.LastTransitionTime
+			}
+			others = append(others, c)
+			continue
+		}
+		dst.Status.Revisions = append(dst.Status.Revisions, ImageRevision{
+			Phase:              ImageRevisionPhase(cond.Type),
+			Status:             ImageRevisionStatus(cond.Status),
+			LastTransitionTime: cond.LastTransitionTime,
+			Target:             cond.Target,
+			TagPolicy:          ImageTagPolicyType(cond.TagPolicy),
+			Revision:           cond.Revision,
+			ResolvedRevision:   cond.ResolvedRevision,
+			Tag:                cond.Tag,
+			Digest:             cond.Digest,
+			Reason:             cond.Reason,
+			Message:            cond.Message,
+			Attempts:           cond.Attempts,
+		})
+	}
+	dst.Status.Conditions = Conditions(dst.Generation, dst.CreationTimestamp, dst.Status.Revisions)
+	// the first failing condition overrides Ready since revisions are not processed while it fails
+	for _, blocking := range []struct{ condType, reason string }{
+		{ConditionTypeTemplateReady, "TemplateNotReady"},
+		{ConditionTypeReconciled, "ReconcileFailed"},
+	} {
+		if c := findCondition(others, blocking.condType); c != nil && c.Status == metav1.ConditionFalse {
+			ready := findCondition(dst.Status.Conditions, ConditionTypeReady)
+			ready.Status, ready.Reason, ready.Message, ready.LastTransitionTime = metav1.ConditionFalse, blocking.reason, c.Message, c.LastTransitionTime
+			break
+		}
+	}
+	dst.Status.Conditions = append(dst.Status.Conditions, others...)
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	dst.Status.Phase = ImagePhase(src.Status.Phase)
+	dst.Status.LastDetectTime = src.Status.LastDetectTime
+	dst.Status.LatestTags = src.Status.LatestTags
+	dst.Status.Latest = nil
+	if src.Status.Latest != nil {
+		if err := convertSpec(&src.Status.Latest, &dst.Status.Latest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// derivedCondition returns true if the condition is derived from revisions and not stored.
+func derivedCondition(t string) bool {
+	return t == ConditionTypeReady || t == ConditionTypeDetecting || t == ConditionTypeBuilding
+}
+
+func findCondition(conds []metav1.Condition, condType string) *metav1.Condition {
+	for i := range conds {
+		if conds[i].Type == condType {
+			return &conds[i]
+		}
+	}
+	return nil
+}
+
+// convertSpec copies fields which have the same schema in both versions.
+func convertSpec(src, dst interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
+
+// Conditions derives Ready, Detecting and Building from revisions.
+func Conditions(generation int64, created metav1.Time, revisions []ImageRevision) []metav1.Condition {
+	checking, uploading, failed, uploaded := []ImageRevision{}, []ImageRevision{}, []ImageRevision{}, []ImageRevision{}
+	for _, rev := range revisions {
+		switch {
+		case rev.Phase == ImageRevisionPhaseChecked && rev.Status == ImageRevisionStatusFalse:
+			checking = append(checking, rev)
+		case rev.Phase == ImageRevisionPhaseUploaded && rev.Status == ImageRevisionStatusFalse:
+			uploading = append(uploading, rev)
+		case rev.Phase == ImageRevisionPhaseUploaded && rev.Status == ImageRevisionStatusFailed:
+			failed = append(failed, rev)
+		case rev.Phase == ImageRevisionPhaseUploaded && rev.Status == ImageRevisionStatusTrue:
+			uploaded = append(uploaded, rev)
+		}
+	}
+	condition := func(t string, status bool, reason, message string, revs []ImageRevision) metav1.Condition {
+		c := metav1.Condition{
+			Type:               t,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			LastTransitionTime: latestTransitionTime(created, revs),
+			Reason:             reason,
+			Message:            message,
+		}
+		if status {
+			c.Status = metav1.ConditionTrue
+		}
+		return c
+	}
+	ret := []metav1.Condition{}
+	switch {
+	case len(failed) > 0:
+		ret = append(ret, condition(ConditionTypeReady, false, "UploadFailed", fmt.Sprintf("failed to upload: %s", describe(failed)), failed))
+	case len(checking) > 0 || len(uploading) > 0:
+		ret = append(ret, condition(ConditionTypeReady, false, "InProgress", "revisions are being checked or uploaded", append(checking, uploading...)))
+	case len(uploaded) == 0:
+		ret = append(ret, condition(ConditionTypeReady, false, "NoRevision", "no revision is uploaded yet", revisions))
+	default:
+		ret = append(ret, condition(ConditionTypeReady, true, "Uploaded", "all revisions are uploaded", uploaded))
+	}
+	if len(checking) > 0 {
+		ret = append(ret, condition(ConditionTypeDetecting, true, "Checking", fmt.Sprintf("waiting for check: %s", describe(checking)), checking))
+	} else {
+		ret = append(ret, condition(ConditionTypeDetecting, false, "Checked", "all detected revisions are checked", revisions))
+	}
+	if len(uploading) > 0 {
+		ret = append(ret, condition(ConditionTypeBuilding, true, "Uploading", fmt.Sprintf("uploading: %s", describe(uploading)), uploading))
+	} else {
+		ret = append(ret, condition(ConditionTypeBuilding, false, "Idle", "no revision is uploading", revisions))
+	}
+	return ret
+}
+
+func latestTransitionTime(created metav1.Time, revs []ImageRevision) metav1.Time {
+	ret := created
+	for _, rev := range revs {
+		if rev.LastTransitionTime != nil && ret.Before(rev.LastTransitionTime) {
+			ret = *rev.LastTransitionTime
+		}
+	}
+	return ret
+}
+
+func describe(revs []ImageRevision) string {
+	s := []string{}
+	for _, rev := range revs {
+		s = append(s, fmt.Sprintf("%s@%s", rev.Target, rev.Revision))
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,248 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImage_Conversion(t *testing.T) {
+	now := metav1.NewTime(time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC))
+	hub := &v1beta1.Image{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Generation: 2},
+		Spec: v1beta1.ImageSpec{
+			TemplateName: "test",
+			Repository: v1beta1.ImageRepository{
+				URL: "https://github.com/takutakahashi/oci-image-operator.git",
+				TagPolicies: []v1beta1.ImageTagPolicy{
+					{Policy: v1beta1.ImageTagPolicyTypeBranchName, Revision: "release/*", Match: v1beta1.ImageTagPolicyMatchGlob},
+					{Policy: v1beta1.ImageTagPolicyTypeSemver, Semver: &v1beta1.ImageTagPolicySemver{Constraint: ">=1.0.0", Strategy: v1beta1.ImageSemverStrategyAll}},
+				},
+				TagTemplate: "{{ .Branch }}",
+			},
+			Targets: []v1beta1.ImageTarget{
+				{
+					Name:       "ghcr.io/takutakahashi/test",
+					Auth:       v1beta1.ImageAuth{Type: v1beta1.ImageAuthTypeBasic, SecretName: "secret"},
+					Context:    "app",
+					Dockerfile: "app/Dockerfile",
+					BuildArgs:  map[string]string{"A": "1"},
+				},
+			},
+			Env:         []corev1.EnvVar{{Name: "ENV", Value: "value"}},
+			RetryPolicy: &v1beta1.ImageRetryPolicy{MaxAttempts: 3, Backoff: &metav1.Duration{Duration: time.Minute}, Jitter: 10},
+		},
+		Status: v1beta1.ImageStatus{
+			Conditions: []v1beta1.ImageCondition{
+				{
+					LastTransitionTime: &now,
+					Type:               v1beta1.ImageConditionTypeChecked,
+					Status:             v1beta1.ImageConditionStatusTrue,
+					TagPolicy:          v1beta1.ImageTagPolicyTypeBranchName,
+					Target:             "ghcr.io/takutakahashi/test",
+					Revision:           "release/1",
+					ResolvedRevision:   "aaa",
+					Tag:                "release-1",
+				},
+				{
+					LastTransitionTime: &now,
+					Type:               v1beta1.ImageConditionTypeUploaded,
+					Status:             v1beta1.ImageConditionStatusTrue,
+					TagPolicy:          v1beta1.ImageTagPolicyTypeBranchName,
+					Target:             "ghcr.io/takutakahashi/test",
+					Revision:           "release/1",
+					ResolvedRevision:   "aaa",
+					Tag:                "release-1",
+					Digest:             "sha256:aaa",
+					Attempts:           2,
+				},
+			},
+			ObservedGeneration: 2,
+			Phase:              v1beta1.ImagePhaseReady,
+			Latest: []v1beta1.ImageLatestStatus{
+				{
+					Target:             "ghcr.io/takutakahashi/test",
+					TagPolicy:          v1beta1.ImageTagPolicyTypeBranchName,
+					Revision:           "release/1",
+					ResolvedRevision:   "aaa",
+					UploadedRevision:   "aaa",
+					UploadedTag:        "release-1",
+					UploadedDigest:     "sha256:aaa",
+					LastTransitionTime: &now,
+				},
+			},
+			LatestTags:     "release-1",
+			LastDetectTime: &now,
+		},
+	}
+	image := &Image{}
+	if err := image.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if len(image.Status.Revisions) != 2 {
+		t.Errorf("revisions = %v", image.Status.Revisions)
+	}
+	if c := findCondition(image.Status.Conditions, ConditionTypeReady); c == nil || c.Status != metav1.ConditionTrue || c.ObservedGeneration != 2 {
+		t.Errorf("Ready condition = %v", c)
+	}
+	got := &v1beta1.Image{}
+	if err := image.ConvertTo(got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(hub, got); diff != "" {
+		t.Errorf("round trip diff: %s", diff)
+	}
+
+	hub.Status.Conditions = append([]v1beta1.ImageCondition{{
+		LastTransitionTime: &now,
+		Type:               v1beta1.ImageConditionTypeTemplateReady,
+		Status:             v1beta1.ImageConditionStatusFalse,
+		Reason:             "RequiredEnvMissing",
+		Message:            "required env is not set: detect/GITHUB_TOKEN",
+	}}, hub.Status.Conditions...)
+	image = &Image{}
+	if err := image.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if len(image.Status.Revisions) != 2 {
+		t.Errorf("revisions = %v", image.Status.Revisions)
+	}
+	if c := findCondition(image.Status.Conditions, ConditionTypeReady); c == nil || c.Status != metav1.ConditionFalse || c.Reason != "TemplateNotReady" {
+		t.Errorf("Ready condition = %v", c)
+	}
+	if c := findCondition(image.Status.Conditions, ConditionTypeTemplateReady); c == nil || c.Status != metav1.ConditionFalse {
+		t.Errorf("TemplateReady condition = %v", c)
+	}
+	hub.Status.Conditions = append(hub.Status.Conditions[:1], append([]v1beta1.ImageCondition{{
+		LastTransitionTime: &now,
+		Type:               v1beta1.ImageConditionTypeReconciled,
+		Status:             v1beta1.ImageConditionStatusFalse,
+		Reason:             "SecretNotFound",
+		Message:            `secrets "registry" not found`,
+	}}, hub.Status.Conditions[1:]...)...)
+	image = &Image{}
+	if err := image.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if c := findCondition(image.Status.Conditions, ConditionTypeReady); c == nil || c.Status != metav1.ConditionFalse || c.Reason != "TemplateNotReady" {
+		t.Errorf("Ready condition = %v", c)
+	}
+	hub.Status.Conditions[0].Status = v1beta1.ImageConditionStatusTrue
+	image = &Image{}
+	if err := image.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if c := findCondition(image.Status.Conditions, ConditionTypeReady); c == nil || c.Status != metav1.ConditionFalse || c.Reason != "ReconcileFailed" {
+		t.Errorf("Ready condition = %v", c)
+	}
+	got = &v1beta1.Image{}
+	if err := image.ConvertTo(got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(hub.Status.Conditions, got.Status.Conditions); diff != "" {
+		t.Errorf("round trip diff: %s", diff)
+	}
+}
+
+func TestImage_ConversionRoundTrip(t *testing.T) {
+	now := metav1.NewTime(time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC))
+	hub := &v1beta1.Image{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Generation: 1},
+		Status: v1beta1.ImageStatus{
+			Conditions: []v1beta1.ImageCondition{
+				{Type: v1beta1.ImageConditionTypeReconciled, Status: v1beta1.ImageConditionStatusTrue, Reason: "Succeeded"},
+				{Type: v1beta1.ImageConditionTypeTemplateReady, Status: v1beta1.ImageConditionStatusTrue, Reason: "Succeeded", LastTransitionTime: &now},
+				{Type: v1beta1.ImageConditionTypeUploaded, Status: v1beta1.ImageConditionStatusFalse, Revision: "main", ResolvedRevision: "bbb"},
+				{Type: v1beta1.ImageConditionTypeChecked, Status: v1beta1.ImageConditionStatusTrue, Revision: "main", ResolvedRevision: "aaa", LastTransitionTime: &now},
+			},
+		},
+	}
+	image := &Image{}
+	if err := image.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	got := &v1beta1.Image{}
+	if err := image.ConvertTo(got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(hub.Status.Conditions, got.Status.Conditions); diff != "" {
+		t.Errorf("v1beta1 round trip diff: %s", diff)
+	}
+	again := &Image{}
+	if err := again.ConvertFrom(got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(image.Status, again.Status); diff != "" {
+		t.Errorf("v1 round trip diff: %s", diff)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	now := metav1.NewTime(time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name      string
+		revisions []ImageRevision
+		want      map[string]metav1.ConditionStatus
+		reason    string
+	}{
+		{
+			name:      "empty",
+			revisions: []ImageRevision{},
+			want:      map[string]metav1.ConditionStatus{ConditionTypeReady: metav1.ConditionFalse, ConditionTypeDetecting: metav1.ConditionFalse, ConditionTypeBuilding: metav1.ConditionFalse},
+			reason:    "NoRevision",
+		},
+		{
+			name: "checking",
+			revisions: []ImageRevision{
+				{Phase: ImageRevisionPhaseChecked, Status: ImageRevisionStatusFalse, Revision: "main", LastTransitionTime: &now},
+			},
+			want:   map[string]metav1.ConditionStatus{ConditionTypeReady: metav1.ConditionFalse, ConditionTypeDetecting: metav1.ConditionTrue, ConditionTypeBuilding: metav1.ConditionFalse},
+			reason: "InProgress",
+		},
+		{
+			name: "building",
+			revisions: []ImageRevision{
+				{Phase: ImageRevisionPhaseChecked, Status: ImageRevisionStatusTrue, Revision: "main"},
+				{Phase: ImageRevisionPhaseUploaded, Status: ImageRevisionStatusFalse, Revision: "main"},
+			},
+			want:   map[string]metav1.ConditionStatus{ConditionTypeReady: metav1.ConditionFalse, ConditionTypeDetecting: metav1.ConditionFalse, ConditionTypeBuilding: metav1.ConditionTrue},
+			reason: "InProgress",
+		},
+		{
+			name: "failed",
+			revisions: []ImageRevision{
+				{Phase: ImageRevisionPhaseUploaded, Status: ImageRevisionStatusTrue, Revision: "v1"},
+				{Phase: ImageRevisionPhaseUploaded, Status: ImageRevisionStatusFailed, Revision: "main"},
+			},
+			want:   map[string]metav1.ConditionStatus{ConditionTypeReady: metav1.ConditionFalse, ConditionTypeDetecting: metav1.ConditionFalse, ConditionTypeBuilding: metav1.ConditionFalse},
+			reason: "UploadFailed",
+		},
+		{
+			name: "ready",
+			revisions: []ImageRevision{
+				{Phase: ImageRevisionPhaseUploaded, Status: ImageRevisionStatusTrue, Revision: "v1"},
+				{Phase: ImageRevisionPhaseUploaded, Status: ImageRevisionStatusCanceled, Revision: "main"},
+			},
+			want:   map[string]metav1.ConditionStatus{ConditionTypeReady: metav1.ConditionTrue, ConditionTypeDetecting: metav1.ConditionFalse, ConditionTypeBuilding: metav1.ConditionFalse},
+			reason: "Uploaded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Conditions(1, now, tt.revisions)
+			for condType, status := range tt.want {
+				c := findCondition(got, condType)
+				if c == nil || c.Status != status {
+					t.Errorf("%s = %v, want %s", condType, c, status)
+				}
+			}
+			if c := findCondition(got, ConditionTypeReady); c.Reason != tt.reason {
+				t.Errorf("Ready reason = %s, want %s", c.Reason, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageSpec defines the desired state of Image
+type ImageSpec struct {
+	TemplateName string `json:"templateName,omitempty"`
+	// TemplateRef refers an ImageFlowTemplate or a ClusterImageFlowTemplate. It is preferred over TemplateName.
+	TemplateRef *ImageFlowTemplateRef `json:"templateRef,omitempty"`
+	// PhaseTemplateRefs overrides the template of each phase.
+	PhaseTemplateRefs *ImagePhaseTemplateRefs `json:"phaseTemplateRefs,omitempty"`
+	Repository        ImageRepository         `json:"repository"`
+	Targets           []ImageTarget           `json:"targets"`
+	Env               []corev1.EnvVar         `json:"env,omitempty"`
+	// ServiceAccountName is the service account of actors. It must exist in the namespace where actors run.
+	// When it is empty, a service account which can only get the Image and update its status is created.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// RetryPolicy retries failed uploads of a revision. Failed uploads are not retried when it is nil.
+	RetryPolicy *ImageRetryPolicy `json:"retryPolicy,omitempty"`
+}
+
+// ImageRetryPolicy retries failed uploads with exponential backoff.
+type ImageRetryPolicy struct {
+	// MaxAttempts is the number of uploads of a revision including the first one.
+	MaxAttempts int32 `json:"maxAttempts"`
+	// Backoff is the delay before the first retry. It doubles on each retry up to 1h. Default is 1m.
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+	// Jitter is the percentage of the backoff which is added at random to spread retries. It is between 0 and 100.
+	Jitter int32 `json:"jitter,omitempty"`
+}
+
+// ImagePhaseTemplateRefs refers the template used by each phase.
+// A phase without ref uses the default-template-<phase> annotation, then the template of the Image.
+type ImagePhaseTemplateRefs struct {
+	Detect *ImageFlowTemplateRef `json:"detect,omitempty"`
+	Check  *ImageFlowTemplateRef `json:"check,omitempty"`
+	Upload *ImageFlowTemplateRef `json:"upload,omitempty"`
+}
+
+type ImageFlowTemplateRef struct {
+	Name string `json:"name"`
+	// Kind is ImageFlowTemplate or ClusterImageFlowTemplate. Default is ImageFlowTemplate.
+	// An ImageFlowTemplate with the same name in the namespace of the Image takes precedence over a ClusterImageFlowTemplate.
+	Kind ImageFlowTemplateKind `json:"kind,omitempty"`
+}
+
+type ImageFlowTemplateKind string
+
+var (
+	ImageFlowTemplateKindNamespaced ImageFlowTemplateKind = "ImageFlowTemplate"
+	ImageFlowTemplateKindCluster    ImageFlowTemplateKind = "ClusterImageFlowTemplate"
+)
+
+type ImageRepository struct {
+	URL         string           `json:"url"`
+	Auth        ImageAuth        `json:"auth,omitempty"`
+	TagPolicies []ImageTagPolicy `json:"tagPolicies,omitempty"`
+	// TagTemplate is a go template of the image tag. ex: "{{ .Branch }}-{{ .ShortSHA }}-{{ .Date }}"
+	// Available fields are Revision, Branch, GitTag, SHA, ShortSHA, Date and Timestamp.
+	// The tag is rendered once when a revision is detected.
+	TagTemplate string `json:"tagTemplate,omitempty"`
+}
+
+type ImageTagPolicy struct {
+	Policy   ImageTagPolicyType `json:"policy,omitempty"`
+	Revision string             `json:"revision,omitempty"`
+	// Match is how Revision is compared with branch names: exact, glob or regex. Default is exact.
+	// glob and regex policies build every matching branch.
+	Match ImageTagPolicyMatch `json:"match,omitempty"`
+	// Semver is used when Policy is semver.
+	Semver *ImageTagPolicySemver `json:"semver,omitempty"`
+}
+
+type ImageTagPolicySemver struct {
+	// Constraint is the range of versions to build. ex: ">=1.4.0 <2.0.0"
+	// All versions match when it is empty.
+	Constraint string `json:"constraint,omitempty"`
+	// IncludePrerelease builds prerelease versions such as v1.5.0-rc.1 too.
+	IncludePrerelease bool `json:"includePrerelease,omitempty"`
+	// Strategy is highest or all. Default is highest.
+	Strategy ImageSemverStrategy `json:"strategy,omitempty"`
+}
+
+type ImageTagPolicyMatch string
+
+var (
+	ImageTagPolicyMatchExact ImageTagPolicyMatch = "exact"
+	ImageTagPolicyMatchGlob  ImageTagPolicyMatch = "glob"
+	ImageTagPolicyMatchRegex ImageTagPolicyMatch = "regex"
+)
+
+type ImageSemverStrategy string
+
+var (
+	ImageSemverStrategyHighest ImageSemverStrategy = "highest"
+	ImageSemverStrategyAll     ImageSemverStrategy = "all"
+)
+
+type ImageTagPolicyType string
+
+var (
+	ImageTagPolicyTypeBranchHash ImageTagPolicyType = "branchHash"
+	ImageTagPolicyTypeBranchName ImageTagPolicyType = "branchName"
+	ImageTagPolicyTypeTagHash    ImageTagPolicyType = "tagHash"
+	ImageTagPolicyTypeTagName    ImageTagPolicyType = "tagName"
+	ImageTagPolicyTypeSemver     ImageTagPolicyType = "semver"
+	ImageTagPolicyTypeUnused     ImageTagPolicyType = "unused"
+)
+
+type ImageTarget struct {
+	Name string    `json:"name"`
+	Auth ImageAuth `json:"auth,omitempty"`
+	// Context is the build context path in the repository. ex: services/api
+	Context string `json:"context,omitempty"`
+	// Dockerfile is the path of Dockerfile in the repository. ex: services/api/Dockerfile
+	Dockerfile string `json:"dockerfile,omitempty"`
+	// BuildArgs are passed to the build as build-time variables.
+	BuildArgs map[string]string `json:"buildArgs,omitempty"`
+	// TagTemplate overrides TagTemplate of the repository for this target.
+	TagTemplate string `json:"tagTemplate,omitempty"`
+}
+
+type ImageAuth struct {
+	Type       ImageAuthType `json:"type"`
+	SecretName string        `json:"secretName"`
+}
+
+type ImageAuthType string
+
+var (
+	// ImageAuthTypeBasic uses the keys username and password of the secret.
+	ImageAuthTypeBasic ImageAuthType = "basic"
+	// ImageAuthTypeDockerConfigJSON uses the entry for the registry host in .dockerconfigjson of the secret.
+	ImageAuthTypeDockerConfigJSON ImageAuthType = "dockerconfigjson"
+	// ImageAuthTypeToken uses the key token of the secret as a bearer token.
+	ImageAuthTypeToken ImageAuthType = "token"
+)
+
+// ImageStatus defines the observed state of Image
+type ImageStatus struct {
+	// Conditions are Ready, Detecting and Building.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Revisions are the states of each revision and target.
+	Revisions []ImageRevision `json:"revisions,omitempty"`
+	// ObservedGeneration is the generation which the controller reconciled last.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Phase is the summary of revisions. Can be Pending, Detecting, Building, Failed, Ready.
+	Phase ImagePhase `json:"phase,omitempty"`
+	// Latest is the latest state of each revision of tag policies and targets.
+	Latest []ImageLatestStatus `json:"latest,omitempty"`
+	// LatestTags lists the uploaded tags of Latest separated by commas. It is shown by kubectl get.
+	LatestTags string `json:"latestTags,omitempty"`
+	// LastDetectTime is the time when the detect actor polled the repository successfully last.
+	LastDetectTime *metav1.Time `json:"lastDetectTime,omitempty"`
+}
+
+type ImagePhase string
+
+var (
+	ImagePhasePending   ImagePhase = "Pending"
+	ImagePhaseDetecting ImagePhase = "Detecting"
+	ImagePhaseBuilding  ImagePhase = "Building"
+	ImagePhaseFailed    ImagePhase = "Failed"
+	ImagePhaseReady     ImagePhase = "Ready"
+)
+
+type ImageLatestStatus struct {
+	Target    string             `json:"target,omitempty"`
+	TagPolicy ImageTagPolicyType `json:"tagPolicy,omitempty"`
+	Revision  string             `json:"revision,omitempty"`
+	// ResolvedRevision is the latest detected commit.
+	ResolvedRevision string `json:"resolvedRevision,omitempty"`
+	// UploadedRevision is the commit of the latest uploaded image.
+	UploadedRevision string `json:"uploadedRevision,omitempty"`
+	// UploadedTag is the tag of the latest uploaded image.
+	UploadedTag string `json:"uploadedTag,omitempty"`
+	// UploadedDigest is the digest of the latest uploaded image when the upload actor reports it.
+	UploadedDigest string `json:"uploadedDigest,omitempty"`
+	// Last time the resolved or uploaded revision transitioned.
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+type ImageRevision struct {
+	// Phase is checked or uploaded.
+	Phase ImageRevisionPhase `json:"phase"`
+	// Status is the result of the phase. Can be True, False, failed, canceled, Unknown.
+	Status ImageRevisionStatus `json:"status"`
+	// Last time the status transitioned.
+	LastTransitionTime *metav1.Time       `json:"lastTransitionTime,omitempty"`
+	Target             string             `json:"target,omitempty"`
+	TagPolicy          ImageTagPolicyType `json:"tagPolicy,omitempty"`
+	Revision           string             `json:"revision,omitempty"`
+	ResolvedRevision   string             `json:"resolvedRevision,omitempty"`
+	Tag                string             `json:"tag,omitempty"`
+	Digest             string             `json:"digest,omitempty"`
+	Reason             string             `json:"reason,omitempty"`
+	Message            string             `json:"message,omitempty"`
+	// Attempts is the number of uploads which have been started for the revision. It is counted when the upload is retried.
+	Attempts int32 `json:"attempts,omitempty"`
+}
+
+type ImageRevisionPhase string
+
+var (
+	ImageRevisionPhaseDetected ImageRevisionPhase = "detected"
+	ImageRevisionPhaseChecked  ImageRevisionPhase = "checked"
+	ImageRevisionPhaseUploaded ImageRevisionPhase = "uploaded"
+)
+
+type ImageRevisionStatus string
+
+var (
+	ImageRevisionStatusTrue     ImageRevisionStatus = "True"
+	ImageRevisionStatusFalse    ImageRevisionStatus = "False"
+	ImageRevisionStatusFailed   ImageRevisionStatus = "failed"
+	ImageRevisionStatusCanceled ImageRevisionStatus = "canceled"
+	ImageRevisionStatusUnknown  ImageRevisionStatus = "Unknown"
+)
+
+const (
+	// ConditionTypeReady is True when every revision is uploaded or exists on the registry.
+	ConditionTypeReady = "Ready"
+	// ConditionTypeDetecting is True while detected revisions are waiting for check.
+	ConditionTypeDetecting = "Detecting"
+	// ConditionTypeBuilding is True while revisions are uploading.
+	ConditionTypeBuilding = "Building"
+	// ConditionTypeTemplateReady is False when the template can not run the Image.
+	ConditionTypeTemplateReady = "TemplateReady"
+	// ConditionTypeDetectorHealthy is False when the detect actor is not running or has not polled recently.
+	ConditionTypeDetectorHealthy = "DetectorHealthy"
+	// ConditionTypeReconciled is False when the Image can't be reconciled until it or the resources which it refers change.
+	ConditionTypeReconciled = "Reconciled"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Tags",type=string,JSONPath=`.status.latestTags`
+//+kubebuilder:printcolumn:name="Generation",type=integer,JSONPath=`.status.observedGeneration`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Image is the Schema for the images API
+type Image struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageSpec   `json:"spec,omitempty"`
+	Status ImageStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImageList contains a list of Image
+type ImageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Image `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Image{}, &ImageList{})
+}
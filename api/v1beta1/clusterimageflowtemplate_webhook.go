@@ -0,0 +1,60 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *ClusterImageFlowTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-build-takutakahashi-dev-v1beta1-clusterimageflowtemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=build.takutakahashi.dev,resources=clusterimageflowtemplates,verbs=create;update,versions=v1beta1,name=vclusterimageflowtemplate.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ClusterImageFlowTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ClusterImageFlowTemplate) ValidateCreate() error {
+	imageflowtemplatelog.Info("validate create", "name", r.Name, "kind", "ClusterImageFlowTemplate")
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ClusterImageFlowTemplate) ValidateUpdate(old runtime.Object) error {
+	imageflowtemplatelog.Info("validate update", "name", r.Name, "kind", "ClusterImageFlowTemplate")
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ClusterImageFlowTemplate) ValidateDelete() error {
+	return nil
+}
+
+func (r *ClusterImageFlowTemplate) validate() error {
+	errs := r.ImageFlowTemplate().ValidateSpec()
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "ClusterImageFlowTemplate"}, r.Name, errs)
+}
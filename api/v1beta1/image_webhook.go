@@ -0,0 +1,349 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"text/template"
+
+	"github.com/Masterminds/semver/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// log is for logging in this package.
+var imagelog = logf.Log.WithName("image-resource")
+
+// imageWebhook defaults and validates Image.
+// The client is used to look up the ImageFlowTemplate.
+type imageWebhook struct {
+	client client.Reader
+}
+
+func (r *Image) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	w := &imageWebhook{client: mgr.GetClient()}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-build-takutakahashi-dev-v1beta1-image,mutating=true,failurePolicy=fail,sideEffects=None,groups=build.takutakahashi.dev,resources=images,verbs=create;update,versions=v1beta1,name=mimage.kb.io,admissionReviewVersions=v1
+
+// Default implements admission.CustomDefaulter
+func (w *imageWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	r, ok := obj.(*Image)
+	if !ok {
+		return fmt.Errorf("expected an Image but got a %T", obj)
+	}
+	imagelog.Info("default", "name", r.Name)
+	r.SetDefaults()
+	return nil
+}
+
+// SetDefaults fills the template name from the default annotation and the default values of policies and auth.
+func (r *Image) SetDefaults() {
+	if r.Spec.TemplateName == "" && r.Spec.TemplateRef == nil {
+		r.Spec.TemplateName = r.Annotations[AnnotationImageFlowTemplateDefaultAll]
+	}
+	defaultTemplateRef(r.Spec.TemplateRef)
+	for _, phase := range ImageFlowPhases {
+		defaultTemplateRef(r.Spec.PhaseTemplateRefs.Get(phase))
+	}
+	for i, policy := range r.Spec.Repository.TagPolicies {
+		switch policy.Policy {
+		case ImageTagPolicyTypeBranchHash, ImageTagPolicyTypeBranchName:
+			if policy.Match == "" {
+				r.Spec.Repository.TagPolicies[i].Match = ImageTagPolicyMatchExact
+			}
+		case ImageTagPolicyTypeSemver:
+			if policy.Semver == nil {
+				r.Spec.Repository.TagPolicies[i].Semver = &ImageTagPolicySemver{}
+			}
+			if r.Spec.Repository.TagPolicies[i].Semver.Strategy == "" {
+				r.Spec.Repository.TagPolicies[i].Semver.Strategy = ImageSemverStrategyHighest
+			}
+		}
+	}
+	defaultAuth(&r.Spec.Repository.Auth)
+	for i := range r.Spec.Targets {
+		defaultAuth(&r.Spec.Targets[i].Auth)
+	}
+	if r.Spec.RetryPolicy != nil && r.Spec.RetryPolicy.Backoff == nil {
+		r.Spec.RetryPolicy.Backoff = &metav1.Duration{Duration: DefaultRetryBackoff}
+	}
+}
+
+func defaultTemplateRef(ref *ImageFlowTemplateRef) {
+	if ref != nil && ref.Kind == "" {
+		ref.Kind = ImageFlowTemplateKindNamespaced
+	}
+}
+
+func defaultAuth(auth *ImageAuth) {
+	if auth.SecretName != "" && auth.Type == "" {
+		auth.Type = ImageAuthTypeBasic
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-build-takutakahashi-dev-v1beta1-image,mutating=false,failurePolicy=fail,sideEffects=None,groups=build.takutakahashi.dev,resources=images,verbs=create;update,versions=v1beta1,name=vimage.kb.io,admissionReviewVersions=v1
+
+// ValidateCreate implements admission.CustomValidator
+func (w *imageWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	r, ok := obj.(*Image)
+	if !ok {
+		return fmt.Errorf("expected an Image but got a %T", obj)
+	}
+	imagelog.Info("validate create", "name", r.Name)
+	return w.validate(ctx, r)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (w *imageWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	r, ok := newObj.(*Image)
+	if !ok {
+		return fmt.Errorf("expected an Image but got a %T", newObj)
+	}
+	imagelog.Info("validate update", "name", r.Name)
+	// finalizers are removed from images which are being deleted even if the template is gone
+	if r.DeletionTimestamp != nil {
+		return nil
+	}
+	return w.validate(ctx, r)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (w *imageWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (w *imageWebhook) validate(ctx context.Context, r *Image) error {
+	errs := r.ValidateSpec()
+	errs = append(errs, w.validateTemplate(ctx, r)...)
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "Image"}, r.Name, errs)
+}
+
+func (w *imageWebhook) validateTemplate(ctx context.Context, r *Image) field.ErrorList {
+	errs := field.ErrorList{}
+	checked := map[ImageFlowTemplateRef]bool{}
+	for _, phase := range ImageFlowPhases {
+		p := templateRefPath(r, phase)
+		ref := r.PhaseTemplateRef(phase)
+		if ref.Name == "" {
+			errs = append(errs, field.Required(p, fmt.Sprintf("template of %s phase is not found. templateName, templateRef or %s annotation is required", phase, AnnotationImageFlowTemplateDefaultAll)))
+			continue
+		}
+		if checked[ref] {
+			continue
+		}
+		checked[ref] = true
+		if _, err := GetTemplate(ctx, w.client, r.Namespace, ref); err != nil {
+			if apierrors.IsNotFound(err) {
+				errs = append(errs, field.NotFound(p, ref.Name))
+			} else {
+				errs = append(errs, field.InternalError(p, err))
+			}
+		}
+	}
+	return errs
+}
+
+// templateRefPath returns the path of the field which refers the template of the phase.
+func templateRefPath(r *Image, phase ImageFlowPhase) *field.Path {
+	spec := field.NewPath("spec")
+	if r.Spec.PhaseTemplateRefs.Get(phase) != nil {
+		return spec.Child("phaseTemplateRefs", string(phase), "name")
+	}
+	if r.Spec.TemplateRef != nil {
+		return spec.Child("templateRef", "name")
+	}
+	return spec.Child("templateName")
+}
+
+// ValidateSpec validates the fields of Image which can be checked without other resources.
+func (r *Image) ValidateSpec() field.ErrorList {
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+	if ref := r.Spec.TemplateRef; ref != nil {
+		if r.Spec.TemplateName != "" {
+			errs = append(errs, field.Forbidden(spec.Child("templateRef"), "templateName and templateRef are exclusive"))
+		}
+		errs = append(errs, validateTemplateKind(spec.Child("templateRef", "kind"), ref.Kind)...)
+	}
+	for _, phase := range ImageFlowPhases {
+		ref := r.Spec.PhaseTemplateRefs.Get(phase)
+		if ref == nil {
+			continue
+		}
+		p := spec.Child("phaseTemplateRefs", string(phase))
+		if ref.Name == "" {
+			errs = append(errs, field.Required(p.Child("name"), ""))
+		}
+		errs = append(errs, validateTemplateKind(p.Child("kind"), ref.Kind)...)
+	}
+	if r.Spec.Repository.URL == "" {
+		errs = append(errs, field.Required(spec.Child("repository", "url"), ""))
+	}
+	errs = append(errs, validateAuth(spec.Child("repository", "auth"), r.Spec.Repository.Auth)...)
+	errs = append(errs, validateTagTemplate(spec.Child("repository", "tagTemplate"), r.Spec.Repository.TagTemplate)...)
+	for i, policy := range r.Spec.Repository.TagPolicies {
+		errs = append(errs, validateTagPolicy(spec.Child("repository", "tagPolicies").Index(i), policy)...)
+	}
+	if len(r.Spec.Targets) == 0 {
+		errs = append(errs, field.Required(spec.Child("targets"), "at least one target is required"))
+	}
+	names := map[string]bool{}
+	for i, target := range r.Spec.Targets {
+		p := spec.Child("targets").Index(i)
+		if target.Name == "" {
+			errs = append(errs, field.Required(p.Child("name"), ""))
+		} else if names[target.Name] {
+			errs = append(errs, field.Duplicate(p.Child("name"), target.Name))
+		}
+		names[target.Name] = true
+		errs = append(errs, validateAuth(p.Child("auth"), target.Auth)...)
+		errs = append(errs, validateTagTemplate(p.Child("tagTemplate"), target.TagTemplate)...)
+	}
+	errs = append(errs, validateRetryPolicy(spec.Child("retryPolicy"), r.Spec.RetryPolicy)...)
+	return errs
+}
+
+func validateTemplateKind(p *field.Path, kind ImageFlowTemplateKind) field.ErrorList {
+	switch kind {
+	case "", ImageFlowTemplateKindNamespaced, ImageFlowTemplateKindCluster:
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(p, kind,
+		[]string{string(ImageFlowTemplateKindNamespaced), string(ImageFlowTemplateKindCluster)})}
+}
+
+func validateTagPolicy(p *field.Path, policy ImageTagPolicy) field.ErrorList {
+	errs := field.ErrorList{}
+	switch policy.Policy {
+	case ImageTagPolicyTypeBranchHash, ImageTagPolicyTypeBranchName:
+		if policy.Revision == "" {
+			errs = append(errs, field.Required(p.Child("revision"), ""))
+		}
+		switch policy.Match {
+		case "", ImageTagPolicyMatchExact:
+		case ImageTagPolicyMatchGlob:
+			if _, err := path.Match(policy.Revision, ""); err != nil {
+				errs = append(errs, field.Invalid(p.Child("revision"), policy.Revision, err.Error()))
+			}
+		case ImageTagPolicyMatchRegex:
+			if _, err := regexp.Compile(policy.Revision); err != nil {
+				errs = append(errs, field.Invalid(p.Child("revision"), policy.Revision, err.Error()))
+			}
+		default:
+			errs = append(errs, field.NotSupported(p.Child("match"), policy.Match,
+				[]string{string(ImageTagPolicyMatchExact), string(ImageTagPolicyMatchGlob), string(ImageTagPolicyMatchRegex)}))
+		}
+	case ImageTagPolicyTypeTagHash, ImageTagPolicyTypeTagName:
+		if policy.Revision == "" {
+			errs = append(errs, field.Required(p.Child("revision"), ""))
+		}
+	case ImageTagPolicyTypeSemver:
+		if policy.Semver == nil {
+			break
+		}
+		if policy.Semver.Constraint != "" {
+			if _, err := semver.NewConstraint(policy.Semver.Constraint); err != nil {
+				errs = append(errs, field.Invalid(p.Child("semver", "constraint"), policy.Semver.Constraint, err.Error()))
+			}
+		}
+		switch policy.Semver.Strategy {
+		case "", ImageSemverStrategyHighest, ImageSemverStrategyAll:
+		default:
+			errs = append(errs, field.NotSupported(p.Child("semver", "strategy"), policy.Semver.Strategy,
+				[]string{string(ImageSemverStrategyHighest), string(ImageSemverStrategyAll)}))
+		}
+	default:
+		errs = append(errs, field.NotSupported(p.Child("policy"), policy.Policy, []string{
+			string(ImageTagPolicyTypeBranchHash),
+			string(ImageTagPolicyTypeBranchName),
+			string(ImageTagPolicyTypeTagHash),
+			string(ImageTagPolicyTypeTagName),
+			string(ImageTagPolicyTypeSemver),
+		}))
+	}
+	if policy.Match != "" && policy.Match != ImageTagPolicyMatchExact &&
+		policy.Policy != ImageTagPolicyTypeBranchHash && policy.Policy != ImageTagPolicyTypeBranchName {
+		errs = append(errs, field.Invalid(p.Child("match"), policy.Match, "patterns are supported only by branch policies"))
+	}
+	return errs
+}
+
+func validateAuth(p *field.Path, auth ImageAuth) field.ErrorList {
+	if auth.Type == "" && auth.SecretName == "" {
+		return nil
+	}
+	errs := field.ErrorList{}
+	if auth.SecretName == "" {
+		errs = append(errs, field.Required(p.Child("secretName"), ""))
+	}
+	return append(errs, validateAuthType(p.Child("type"), auth.Type)...)
+}
+
+func validateAuthType(p *field.Path, t ImageAuthType) field.ErrorList {
+	switch t {
+	case "", ImageAuthTypeBasic, ImageAuthTypeDockerConfigJSON, ImageAuthTypeToken:
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(p, t, []string{
+		string(ImageAuthTypeBasic), string(ImageAuthTypeDockerConfigJSON), string(ImageAuthTypeToken),
+	})}
+}
+
+func validateRetryPolicy(p *field.Path, policy *ImageRetryPolicy) field.ErrorList {
+	if policy == nil {
+		return nil
+	}
+	errs := field.ErrorList{}
+	if policy.MaxAttempts < 1 {
+		errs = append(errs, field.Invalid(p.Child("maxAttempts"), policy.MaxAttempts, "must be greater than 0"))
+	}
+	if policy.Backoff != nil && policy.Backoff.Duration < 0 {
+		errs = append(errs, field.Invalid(p.Child("backoff"), policy.Backoff.Duration.String(), "must not be negative"))
+	}
+	if policy.Jitter < 0 || policy.Jitter > 100 {
+		errs = append(errs, field.Invalid(p.Child("jitter"), policy.Jitter, "must be between 0 and 100"))
+	}
+	return errs
+}
+
+func validateTagTemplate(p *field.Path, tmpl string) field.ErrorList {
+	if tmpl == "" {
+		return nil
+	}
+	if _, err := template.New("tag").Parse(tmpl); err != nil {
+		return field.ErrorList{field.Invalid(p, tmpl, err.Error())}
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package v1beta1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newWebhookTestImage() *Image {
+	return &Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+		},
+		Spec: ImageSpec{
+			TemplateName: "test",
+			Repository: ImageRepository{
+				URL: "https://github.com/takutakahashi/oci-image-operator.git",
+				TagPolicies: []ImageTagPolicy{
+					{Policy: ImageTagPolicyTypeBranchHash, Revision: "master"},
+				},
+			},
+			Targets: []ImageTarget{
+				{Name: "ghcr.io/takutakahashi/test"},
+			},
+		},
+	}
+}
+
+func TestImage_SetDefaults(t *testing.T) {
+	image := newWebhookTestImage()
+	image.Spec.TemplateName = ""
+	image.Annotations = map[string]string{AnnotationImageFlowTemplateDefaultAll: "default"}
+	image.Spec.Repository.TagPolicies = append(image.Spec.Repository.TagPolicies, ImageTagPolicy{Policy: ImageTagPolicyTypeSemver})
+	image.Spec.Targets[0].Auth = ImageAuth{SecretName: "secret"}
+	image.Spec.RetryPolicy = &ImageRetryPolicy{MaxAttempts: 3}
+	image.SetDefaults()
+
+	want := newWebhookTestImage()
+	want.Spec.TemplateName = "default"
+	want.Annotations = map[string]string{AnnotationImageFlowTemplateDefaultAll: "default"}
+	want.Spec.Repository.TagPolicies = []ImageTagPolicy{
+		{Policy: ImageTagPolicyTypeBranchHash, Revision: "master", Match: ImageTagPolicyMatchExact},
+		{Policy: ImageTagPolicyTypeSemver, Semver: &ImageTagPolicySemver{Strategy: ImageSemverStrategyHighest}},
+	}
+	want.Spec.Targets[0].Auth = ImageAuth{Type: ImageAuthTypeBasic, SecretName: "secret"}
+	want.Spec.RetryPolicy = &ImageRetryPolicy{MaxAttempts: 3, Backoff: &metav1.Duration{Duration: DefaultRetryBackoff}}
+	if diff := cmp.Diff(want, image); diff != "" {
+		t.Errorf("SetDefaults() diff: %s", diff)
+	}
+}
+
+func TestImageWebhook_ValidateCreate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ImageFlowTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+	}, &ClusterImageFlowTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+	}).Build()
+	w := &imageWebhook{client: c}
+	tests := []struct {
+		name    string
+		modify  func(*Image)
+		wantErr bool
+	}{
+		{
+			name:   "ok",
+			modify: func(i *Image) {},
+		},
+		{
+			name:    "empty_targets",
+			modify:  func(i *Image) { i.Spec.Targets = nil },
+			wantErr: true,
+		},
+		{
+			name: "duplicated_targets",
+			modify: func(i *Image) {
+				i.Spec.Targets = append(i.Spec.Targets, i.Spec.Targets[0])
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown_policy",
+			modify: func(i *Image) {
+				i.Spec.Repository.TagPolicies[0].Policy = "unknown"
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid_regex",
+			modify: func(i *Image) {
+				i.Spec.Repository.TagPolicies[0].Revision = "release/("
+				i.Spec.Repository.TagPolicies[0].Match = ImageTagPolicyMatchRegex
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid_semver",
+			modify: func(i *Image) {
+				i.Spec.Repository.TagPolicies[0] = ImageTagPolicy{
+					Policy: ImageTagPolicyTypeSemver,
+					Semver: &ImageTagPolicySemver{Constraint: "invalid"},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid_tag_template",
+			modify: func(i *Image) {
+				i.Spec.Repository.TagTemplate = "{{ .Branch "
+			},
+			wantErr: true,
+		},
+		{
+			name: "dockerconfigjson_auth",
+			modify: func(i *Image) {
+				i.Spec.Targets[0].Auth = ImageAuth{Type: ImageAuthTypeDockerConfigJSON, SecretName: "secret"}
+			},
+		},
+		{
+			name: "unknown_auth_type",
+			modify: func(i *Image) {
+				i.Spec.Targets[0].Auth = ImageAuth{Type: "unknown", SecretName: "secret"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "retry_policy",
+			modify: func(i *Image) {
+				i.Spec.RetryPolicy = &ImageRetryPolicy{MaxAttempts: 3, Backoff: &metav1.Duration{Duration: time.Minute}, Jitter: 20}
+			},
+		},
+		{
+			name: "invalid_retry_policy",
+			modify: func(i *Image) {
+				i.Spec.RetryPolicy = &ImageRetryPolicy{MaxAttempts: 0, Jitter: 120}
+			},
+			wantErr: true,
+		},
+		{
+			name: "template_not_found",
+			modify: func(i *Image) {
+				i.Spec.TemplateName = "notfound"
+			},
+			wantErr: true,
+		},
+		{
+			name: "template_from_annotation",
+			modify: func(i *Image) {
+				i.Spec.TemplateName = ""
+				i.Annotations = map[string]string{AnnotationImageFlowTemplateDefaultAll: "test"}
+			},
+		},
+		{
+			name: "cluster_template",
+			modify: func(i *Image) {
+				i.Spec.TemplateName = ""
+				i.Spec.TemplateRef = &ImageFlowTemplateRef{Name: "cluster", Kind: ImageFlowTemplateKindCluster}
+			},
+		},
+		{
+			name: "cluster_template_as_namespaced",
+			modify: func(i *Image) {
+				i.Spec.TemplateName = ""
+				i.Spec.TemplateRef = &ImageFlowTemplateRef{Name: "cluster"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown_template_kind",
+			modify: func(i *Image) {
+				i.Spec.TemplateName = ""
+				i.Spec.TemplateRef = &ImageFlowTemplateRef{Name: "test", Kind: "unknown"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "template_name_and_ref",
+			modify: func(i *Image) {
+				i.Spec.TemplateRef = &ImageFlowTemplateRef{Name: "test"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "phase_templates",
+			modify: func(i *Image) {
+				i.Spec.TemplateName = ""
+				i.Spec.PhaseTemplateRefs = &ImagePhaseTemplateRefs{
+					Detect: &ImageFlowTemplateRef{Name: "test"},
+					Check:  &ImageFlowTemplateRef{Name: "cluster", Kind: ImageFlowTemplateKindCluster},
+				}
+				i.Annotations = map[string]string{AnnotationImageFlowTemplateDefaultUpload: "test"}
+			},
+		},
+		{
+			name: "phase_template_missing",
+			modify: func(i *Image) {
+				i.Spec.TemplateName = ""
+				i.Spec.PhaseTemplateRefs = &ImagePhaseTemplateRefs{
+					Detect: &ImageFlowTemplateRef{Name: "test"},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "no_template",
+			modify: func(i *Image) {
+				i.Spec.TemplateName = ""
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := newWebhookTestImage()
+			tt.modify(image)
+			if err := w.ValidateCreate(context.Background(), image); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImageFlowTemplate_ValidateCreate(t *testing.T) {
+	actor := &ContainerApplyConfiguration{Image: pointer.String("ghcr.io/takutakahashi/oci-image-operator/actor-github:beta")}
+	tests := []struct {
+		name    string
+		spec    ImageFlowTemplateSpec
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			spec: ImageFlowTemplateSpec{
+				Detect: ImageFlowTemplateSpecTemplate{Actor: actor, RequiredEnv: []string{"GITHUB_TOKEN"}},
+				Check:  ImageFlowTemplateSpecTemplate{Actor: actor},
+				Upload: ImageFlowTemplateSpecTemplate{Actor: actor},
+			},
+		},
+		{
+			name: "no_actor",
+			spec: ImageFlowTemplateSpec{
+				Detect: ImageFlowTemplateSpecTemplate{Actor: actor},
+				Check:  ImageFlowTemplateSpecTemplate{Actor: actor},
+			},
+			wantErr: true,
+		},
+		{
+			name: "inherited_actor",
+			spec: ImageFlowTemplateSpec{
+				BaseImage: "base",
+				Upload:    ImageFlowTemplateSpecTemplate{Actor: &ContainerApplyConfiguration{}},
+			},
+		},
+		{
+			name: "sidecars",
+			spec: ImageFlowTemplateSpec{
+				Detect: ImageFlowTemplateSpecTemplate{Actor: actor},
+				Check:  ImageFlowTemplateSpecTemplate{Actor: actor},
+				Upload: ImageFlowTemplateSpecTemplate{
+					Actor:          actor,
+					InitContainers: []ContainerApplyConfiguration{{Name: pointer.String("init"), Image: pointer.String("busybox")}},
+					Sidecars:       []ContainerApplyConfiguration{{Name: pointer.String("dind"), Image: pointer.String("docker:dind")}},
+				},
+			},
+		},
+		{
+			name: "duplicated_container_name",
+			spec: ImageFlowTemplateSpec{
+				Detect: ImageFlowTemplateSpecTemplate{Actor: actor},
+				Check:  ImageFlowTemplateSpecTemplate{Actor: actor},
+				Upload: ImageFlowTemplateSpecTemplate{
+					Actor:    actor,
+					Sidecars: []ContainerApplyConfiguration{{Name: pointer.String("main"), Image: pointer.String("docker:dind")}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "auth_env_without_key",
+			spec: ImageFlowTemplateSpec{
+				Detect: ImageFlowTemplateSpecTemplate{Actor: actor, AuthEnv: []ImageFlowTemplateAuthEnv{{Name: "GITHUB_TOKEN"}}},
+				Check:  ImageFlowTemplateSpecTemplate{Actor: actor},
+				Upload: ImageFlowTemplateSpecTemplate{Actor: actor},
+			},
+			wantErr: true,
+		},
+		{
+			name: "reserved_volume",
+			spec: ImageFlowTemplateSpec{
+				Detect: ImageFlowTemplateSpecTemplate{Actor: actor, Volumes: []VolumeApplyConfiguration{{Name: pointer.String("tmpdir")}}},
+				Check:  ImageFlowTemplateSpecTemplate{Actor: actor},
+				Upload: ImageFlowTemplateSpecTemplate{Actor: actor},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no_actor_image",
+			spec: ImageFlowTemplateSpec{
+				Detect: ImageFlowTemplateSpecTemplate{Actor: &ContainerApplyConfiguration{}},
+				Check:  ImageFlowTemplateSpecTemplate{Actor: actor},
+				Upload: ImageFlowTemplateSpecTemplate{Actor: actor},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ImageFlowTemplate{Spec: tt.spec}
+			if err := r.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// MergeSpec merges child over base. BaseImage of the child is kept.
+func MergeSpec(base, child ImageFlowTemplateSpec) (ImageFlowTemplateSpec, error) {
+	ret := ImageFlowTemplateSpec{BaseImage: child.BaseImage}
+	var err error
+	if ret.Detect, err = MergeSpecTemplate(base.Detect, child.Detect); err != nil {
+		return ret, err
+	}
+	if ret.Check, err = MergeSpecTemplate(base.Check, child.Check); err != nil {
+		return ret, err
+	}
+	if ret.Upload, err = MergeSpecTemplate(base.Upload, child.Upload); err != nil {
+		return ret, err
+	}
+	return ret, nil
+}
+
+// MergeSpecTemplate merges child over base with strategic merge patch.
+// Lists are merged by their keys like kubectl apply, ex: env, volumes and sidecars by name.
+// Pod options and authEnv of the child replace those of the base except nodeSelector and imagePullSecrets which are merged.
+func MergeSpecTemplate(base, child ImageFlowTemplateSpecTemplate) (ImageFlowTemplateSpecTemplate, error) {
+	ret := *child.DeepCopy()
+	switch {
+	case base.Actor == nil:
+	case child.Actor == nil:
+		ret.Actor = base.Actor.DeepCopy()
+	default:
+		actor := &ContainerApplyConfiguration{}
+		if err := strategicMerge(base.Actor, child.Actor, actor, corev1.Container{}); err != nil {
+			return ret, err
+		}
+		ret.Actor = actor
+	}
+	if len(base.Volumes) > 0 {
+		type volumes struct {
+			Volumes []VolumeApplyConfiguration `json:"volumes,omitempty"`
+		}
+		merged := &volumes{}
+		if err := strategicMerge(volumes{base.Volumes}, volumes{child.Volumes}, merged, corev1.PodSpec{}); err != nil {
+			return ret, err
+		}
+		ret.Volumes = merged.Volumes
+	}
+	if len(base.InitContainers) > 0 || len(base.Sidecars) > 0 {
+		type containers struct {
+			InitContainers []ContainerApplyConfiguration `json:"initContainers,omitempty"`
+			Sidecars       []ContainerApplyConfiguration `json:"containers,omitempty"`
+		}
+		merged := &containers{}
+		if err := strategicMerge(containers{base.InitContainers, base.Sidecars}, containers{child.InitContainers, child.Sidecars}, merged, corev1.PodSpec{}); err != nil {
+			return ret, err
+		}
+		ret.InitContainers, ret.Sidecars = merged.InitContainers, merged.Sidecars
+	}
+	ret.RequiredEnv = mergeStrings(base.RequiredEnv, child.RequiredEnv)
+	if ret.Resources == nil && base.Resources != nil {
+		ret.Resources = base.Resources.DeepCopy()
+	}
+	if len(base.NodeSelector) > 0 {
+		ret.NodeSelector = map[string]string{}
+		for k, v := range base.NodeSelector {
+			ret.NodeSelector[k] = v
+		}
+		for k, v := range child.NodeSelector {
+			ret.NodeSelector[k] = v
+		}
+	}
+	if len(ret.Tolerations) == 0 && len(base.Tolerations) > 0 {
+		ret.Tolerations = append([]corev1.Toleration{}, base.Tolerations...)
+	}
+	if ret.Affinity == nil && base.Affinity != nil {
+		ret.Affinity = base.Affinity.DeepCopy()
+	}
+	if ret.PodSecurityContext == nil && base.PodSecurityContext != nil {
+		ret.PodSecurityContext = base.PodSecurityContext.DeepCopy()
+	}
+	if ret.PriorityClassName == "" {
+		ret.PriorityClassName = base.PriorityClassName
+	}
+	if len(ret.AuthEnv) == 0 && len(base.AuthEnv) > 0 {
+		ret.AuthEnv = append([]ImageFlowTemplateAuthEnv{}, base.AuthEnv...)
+	}
+	for _, s := range base.ImagePullSecrets {
+		found := false
+		for _, c := range child.ImagePullSecrets {
+			if s.Name == c.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ret.ImagePullSecrets = append(ret.ImagePullSecrets, s)
+		}
+	}
+	return ret, nil
+}
+
+// strategicMerge merges child over base and stores the result to out.
+// dataStruct is the core type which has the patch strategy of the fields.
+func strategicMerge(base, child, out, dataStruct interface{}) error {
+	original, err := json.Marshal(base)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(child)
+	if err != nil {
+		return err
+	}
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, dataStruct)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(merged, out)
+}
+
+func mergeStrings(base, child []string) []string {
+	if len(base) == 0 {
+		return child
+	}
+	ret := append([]string{}, base...)
+	for _, s := range child {
+		found := false
+		for _, b := range base {
+			if s == b {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,147 @@
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestMergeSpecTemplate(t *testing.T) {
+	env := func(name, value string) corev1apply.EnvVarApplyConfiguration {
+		return *corev1apply.EnvVar().WithName(name).WithValue(value)
+	}
+	volume := func(name string) VolumeApplyConfiguration {
+		return VolumeApplyConfiguration(*corev1apply.Volume().WithName(name).WithEmptyDir(corev1apply.EmptyDirVolumeSource()))
+	}
+	base := ImageFlowTemplateSpecTemplate{
+		Actor: &ContainerApplyConfiguration{
+			Name:  pointer.String("main"),
+			Image: pointer.String("ghcr.io/takutakahashi/oci-image-operator/actor-github:beta"),
+			Env:   []corev1apply.EnvVarApplyConfiguration{env("GITHUB_ORG", "takutakahashi"), env("GITHUB_REPO", "base")},
+		},
+		Volumes:     []VolumeApplyConfiguration{volume("cache")},
+		RequiredEnv: []string{"GITHUB_TOKEN"},
+	}
+	tests := []struct {
+		name  string
+		child ImageFlowTemplateSpecTemplate
+		want  ImageFlowTemplateSpecTemplate
+	}{
+		{
+			name:  "empty_child",
+			child: ImageFlowTemplateSpecTemplate{},
+			want:  base,
+		},
+		{
+			name: "override_env",
+			child: ImageFlowTemplateSpecTemplate{
+				Actor: &ContainerApplyConfiguration{
+					Env: []corev1apply.EnvVarApplyConfiguration{env("GITHUB_REPO", "child"), env("EXTRA", "1")},
+				},
+				Volumes:     []VolumeApplyConfiguration{volume("tmp")},
+				RequiredEnv: []string{"GITHUB_TOKEN", "EXTRA"},
+			},
+			want: ImageFlowTemplateSpecTemplate{
+				Actor: &ContainerApplyConfiguration{
+					Name:  pointer.String("main"),
+					Image: pointer.String("ghcr.io/takutakahashi/oci-image-operator/actor-github:beta"),
+					Env:   []corev1apply.EnvVarApplyConfiguration{env("GITHUB_ORG", "takutakahashi"), env("GITHUB_REPO", "child"), env("EXTRA", "1")},
+				},
+				Volumes:     []VolumeApplyConfiguration{volume("tmp"), volume("cache")},
+				RequiredEnv: []string{"GITHUB_TOKEN", "EXTRA"},
+			},
+		},
+		{
+			name: "override_image",
+			child: ImageFlowTemplateSpecTemplate{
+				Actor: &ContainerApplyConfiguration{Image: pointer.String("actor:v2")},
+			},
+			want: ImageFlowTemplateSpecTemplate{
+				Actor: &ContainerApplyConfiguration{
+					Name:  pointer.String("main"),
+					Image: pointer.String("actor:v2"),
+					Env:   base.Actor.Env,
+				},
+				Volumes:     base.Volumes,
+				RequiredEnv: base.RequiredEnv,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeSpecTemplate(base, tt.child)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("MergeSpecTemplate() diff: %s", diff)
+			}
+		})
+	}
+	// base must not be modified
+	if len(base.Actor.Env) != 2 || *base.Actor.Env[1].Value != "base" {
+		t.Errorf("base is modified: %v", base.Actor.Env)
+	}
+}
+
+func TestMergeSpecTemplate_PodOptions(t *testing.T) {
+	base := ImageFlowTemplateSpecTemplate{
+		Resources:          &corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}},
+		NodeSelector:       map[string]string{"arch": "amd64", "pool": "default"},
+		Tolerations:        []corev1.Toleration{{Key: "base"}},
+		PriorityClassName:  "low",
+		ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "base"}, {Name: "shared"}},
+		PodSecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: pointer.Bool(false)},
+	}
+	child := ImageFlowTemplateSpecTemplate{
+		NodeSelector:      map[string]string{"pool": "build"},
+		PriorityClassName: "high",
+		ImagePullSecrets:  []corev1.LocalObjectReference{{Name: "shared"}, {Name: "child"}},
+	}
+	got, err := MergeSpecTemplate(base, child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ImageFlowTemplateSpecTemplate{
+		Resources:          base.Resources,
+		NodeSelector:       map[string]string{"arch": "amd64", "pool": "build"},
+		Tolerations:        []corev1.Toleration{{Key: "base"}},
+		PriorityClassName:  "high",
+		ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "shared"}, {Name: "child"}, {Name: "base"}},
+		PodSecurityContext: base.PodSecurityContext,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MergeSpecTemplate() diff: %s", diff)
+	}
+}
+
+func TestMergeSpecTemplate_Containers(t *testing.T) {
+	base := ImageFlowTemplateSpecTemplate{
+		InitContainers: []ContainerApplyConfiguration{{Name: pointer.String("init"), Image: pointer.String("busybox")}},
+		Sidecars: []ContainerApplyConfiguration{
+			{Name: pointer.String("dind"), Image: pointer.String("docker:dind")},
+			{Name: pointer.String("proxy"), Image: pointer.String("proxy:v1")},
+		},
+	}
+	child := ImageFlowTemplateSpecTemplate{
+		Sidecars: []ContainerApplyConfiguration{{Name: pointer.String("proxy"), Image: pointer.String("proxy:v2")}},
+	}
+	got, err := MergeSpecTemplate(base, child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ImageFlowTemplateSpecTemplate{
+		InitContainers: base.InitContainers,
+		Sidecars: []ContainerApplyConfiguration{
+			{Name: pointer.String("dind"), Image: pointer.String("docker:dind")},
+			{Name: pointer.String("proxy"), Image: pointer.String("proxy:v2")},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MergeSpecTemplate() diff: %s", diff)
+	}
+}
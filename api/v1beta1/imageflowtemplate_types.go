@@ -19,6 +19,7 @@ package v1beta1
 import (
 	"encoding/json"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
 )
@@ -30,6 +31,9 @@ var AnnotationImageFlowTemplateDefaultAll string = "build.takutakahashi.dev/defa
 
 // ImageFlowTemplateSpec defines the desired state of ImageFlowTemplate
 type ImageFlowTemplateSpec struct {
+	// BaseImage is the name of the template which this template extends.
+	// The fields of this template are merged over the base template with strategic merge patch.
+	// A namespaced template in the same namespace is preferred over a ClusterImageFlowTemplate.
 	BaseImage string                        `json:"baseImage,omitempty"`
 	Detect    ImageFlowTemplateSpecTemplate `json:"detect,omitempty"`
 	Check     ImageFlowTemplateSpecTemplate `json:"check,omitempty"`
@@ -67,19 +71,69 @@ func (c *VolumeApplyConfiguration) DeepCopy() *VolumeApplyConfiguration {
 }
 
 type ImageFlowTemplateSpecTemplate struct {
-	Actor       *ContainerApplyConfiguration `json:"actor,omitempty"`
-	Volumes     []VolumeApplyConfiguration   `json:"volumes,omitempty"`
-	RequiredEnv []string                     `json:"requiredEnv,omitempty"`
+	Actor *ContainerApplyConfiguration `json:"actor,omitempty"`
+	// Volumes are added to the actor pod. Mount them with volumeMounts of the actor, init containers or sidecars.
+	// tmpdir is reserved for the work directory of the actor, and it can be mounted by other containers too.
+	Volumes     []VolumeApplyConfiguration `json:"volumes,omitempty"`
+	RequiredEnv []string                   `json:"requiredEnv,omitempty"`
+	// InitContainers run before the actor. ex: fetching credentials
+	InitContainers []ContainerApplyConfiguration `json:"initContainers,omitempty"`
+	// Sidecars run beside the actor. ex: docker-in-docker
+	Sidecars []ContainerApplyConfiguration `json:"sidecars,omitempty"`
+	// Resources of the actor container. It takes precedence over actor.resources.
+	// Default is requests cpu 50m, memory 50Mi and limits cpu 300m, memory 200Mi.
+	Resources         *corev1.ResourceRequirements  `json:"resources,omitempty"`
+	NodeSelector      map[string]string             `json:"nodeSelector,omitempty"`
+	Tolerations       []corev1.Toleration           `json:"tolerations,omitempty"`
+	Affinity          *corev1.Affinity              `json:"affinity,omitempty"`
+	PriorityClassName string                        `json:"priorityClassName,omitempty"`
+	ImagePullSecrets  []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// PodSecurityContext is merged over the default which is runAsNonRoot and RuntimeDefault seccomp profile.
+	// securityContext of the actor is merged over the default
+	// which disallows privilege escalation, drops all capabilities and makes the root filesystem read-only.
+	// Init containers and sidecars get the default only when they don't set their own securityContext.
+	// Set fields explicitly to relax them, ex: runAsNonRoot: false for actors which build as root.
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+	// AuthEnv maps keys of the auth secret to env of the actor.
+	// Detect gets the auth of the repository, check and upload get the auth of the target.
+	// The prefix of the default env is REPOSITORY_AUTH for detect and REGISTRY_AUTH for check and upload.
+	// Defaults are <prefix>_USERNAME and <prefix>_PASSWORD from username and password for basic,
+	// <prefix>_DOCKERCONFIGJSON from .dockerconfigjson for dockerconfigjson and <prefix>_TOKEN from token for token.
+	AuthEnv []ImageFlowTemplateAuthEnv `json:"authEnv,omitempty"`
+}
+
+// ImageFlowTemplateAuthEnv sets the value of Key in the auth secret to the env Name.
+type ImageFlowTemplateAuthEnv struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// Type limits the env to the auth of the type. Empty means all types.
+	Type ImageAuthType `json:"type,omitempty"`
 }
 
 // ImageFlowTemplateStatus defines the observed state of ImageFlowTemplate
 type ImageFlowTemplateStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the generation which the controller validated last.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are Ready and RequiredEnvSatisfied.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Images are the Images which refer this template. format: namespace/name
+	Images []string `json:"images,omitempty"`
 }
 
+const (
+	// ImageFlowTemplateConditionTypeReady is True when the template and its base templates are valid.
+	ImageFlowTemplateConditionTypeReady = "Ready"
+	// ImageFlowTemplateConditionTypeRequiredEnvSatisfied is True when every Image referring the template has RequiredEnv.
+	ImageFlowTemplateConditionTypeRequiredEnvSatisfied = "RequiredEnvSatisfied"
+)
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Env",type=string,JSONPath=`.status.conditions[?(@.type=="RequiredEnvSatisfied")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ImageFlowTemplate is the Schema for the imageflowtemplates API
 type ImageFlowTemplate struct {
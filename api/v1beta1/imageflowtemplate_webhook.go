@@ -0,0 +1,146 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var imageflowtemplatelog = logf.Log.WithName("imageflowtemplate-resource")
+
+func (r *ImageFlowTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-build-takutakahashi-dev-v1beta1-imageflowtemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=build.takutakahashi.dev,resources=imageflowtemplates,verbs=create;update,versions=v1beta1,name=vimageflowtemplate.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ImageFlowTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ImageFlowTemplate) ValidateCreate() error {
+	imageflowtemplatelog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ImageFlowTemplate) ValidateUpdate(old runtime.Object) error {
+	imageflowtemplatelog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ImageFlowTemplate) ValidateDelete() error {
+	return nil
+}
+
+func (r *ImageFlowTemplate) validate() error {
+	errs := r.ValidateSpec()
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "ImageFlowTemplate"}, r.Name, errs)
+}
+
+// ValidateSpec validates that every phase has an actor.
+// Actors can be omitted when the template extends a base template.
+func (r *ImageFlowTemplate) ValidateSpec() field.ErrorList {
+	return r.validateSpec(r.Spec.BaseImage != "")
+}
+
+// ValidateResolvedSpec validates the template which is merged with its base templates.
+func (r *ImageFlowTemplate) ValidateResolvedSpec() field.ErrorList {
+	return r.validateSpec(false)
+}
+
+func (r *ImageFlowTemplate) validateSpec(inherited bool) field.ErrorList {
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+	phases := []struct {
+		name     string
+		template ImageFlowTemplateSpecTemplate
+	}{
+		{"detect", r.Spec.Detect},
+		{"check", r.Spec.Check},
+		{"upload", r.Spec.Upload},
+	}
+	for _, phase := range phases {
+		errs = append(errs, validatePhase(spec.Child(phase.name), phase.template, inherited)...)
+	}
+	return errs
+}
+
+func validatePhase(p *field.Path, tmpl ImageFlowTemplateSpecTemplate, inherited bool) field.ErrorList {
+	errs := field.ErrorList{}
+	if tmpl.Actor == nil && !inherited {
+		return append(errs, field.Required(p.Child("actor"), ""))
+	}
+	if tmpl.Actor != nil && !inherited && (tmpl.Actor.Image == nil || *tmpl.Actor.Image == "") {
+		errs = append(errs, field.Required(p.Child("actor", "image"), ""))
+	}
+	names := map[string]bool{"main": true}
+	for _, c := range []struct {
+		name       string
+		containers []ContainerApplyConfiguration
+	}{
+		{"initContainers", tmpl.InitContainers},
+		{"sidecars", tmpl.Sidecars},
+	} {
+		for i, container := range c.containers {
+			cp := p.Child(c.name).Index(i)
+			switch {
+			case container.Name == nil || *container.Name == "":
+				errs = append(errs, field.Required(cp.Child("name"), ""))
+			case names[*container.Name]:
+				errs = append(errs, field.Duplicate(cp.Child("name"), *container.Name))
+			default:
+				names[*container.Name] = true
+			}
+			if !inherited && (container.Image == nil || *container.Image == "") {
+				errs = append(errs, field.Required(cp.Child("image"), ""))
+			}
+		}
+	}
+	for i, volume := range tmpl.Volumes {
+		if volume.Name != nil && *volume.Name == "tmpdir" {
+			errs = append(errs, field.Invalid(p.Child("volumes").Index(i).Child("name"), *volume.Name, "tmpdir is reserved"))
+		}
+	}
+	for i, env := range tmpl.AuthEnv {
+		if env.Name == "" {
+			errs = append(errs, field.Required(p.Child("authEnv").Index(i).Child("name"), ""))
+		}
+		if env.Key == "" {
+			errs = append(errs, field.Required(p.Child("authEnv").Index(i).Child("key"), ""))
+		}
+		errs = append(errs, validateAuthType(p.Child("authEnv").Index(i).Child("type"), env.Type)...)
+	}
+	for i, env := range tmpl.RequiredEnv {
+		if env == "" {
+			errs = append(errs, field.Required(p.Child("requiredEnv").Index(i), ""))
+		}
+	}
+	return errs
+}
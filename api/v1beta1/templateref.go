@@ -0,0 +1,186 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResolvedTemplateRef returns the template referenced by the Image.
+// templateRef is preferred over templateName, and the default-template-all annotation is used when both are empty.
+func (r *Image) ResolvedTemplateRef() ImageFlowTemplateRef {
+	if r.Spec.TemplateRef != nil {
+		ref := *r.Spec.TemplateRef
+		if ref.Kind == "" {
+			ref.Kind = ImageFlowTemplateKindNamespaced
+		}
+		return ref
+	}
+	name := r.Spec.TemplateName
+	if name == "" {
+		name = r.Annotations[AnnotationImageFlowTemplateDefaultAll]
+	}
+	return ImageFlowTemplateRef{Name: name, Kind: ImageFlowTemplateKindNamespaced}
+}
+
+type ImageFlowPhase string
+
+var (
+	ImageFlowPhaseDetect ImageFlowPhase = "detect"
+	ImageFlowPhaseCheck  ImageFlowPhase = "check"
+	ImageFlowPhaseUpload ImageFlowPhase = "upload"
+)
+
+var ImageFlowPhases = []ImageFlowPhase{ImageFlowPhaseDetect, ImageFlowPhaseCheck, ImageFlowPhaseUpload}
+
+// Get returns the ref of the phase. It returns nil if refs is nil.
+func (refs *ImagePhaseTemplateRefs) Get(phase ImageFlowPhase) *ImageFlowTemplateRef {
+	if refs == nil {
+		return nil
+	}
+	switch phase {
+	case ImageFlowPhaseDetect:
+		return refs.Detect
+	case ImageFlowPhaseCheck:
+		return refs.Check
+	case ImageFlowPhaseUpload:
+		return refs.Upload
+	}
+	return nil
+}
+
+// PhaseTemplateRef returns the template used by the phase.
+// phaseTemplateRefs is preferred, then the default-template-<phase> annotation, then ResolvedTemplateRef.
+func (r *Image) PhaseTemplateRef(phase ImageFlowPhase) ImageFlowTemplateRef {
+	ref := r.Spec.PhaseTemplateRefs.Get(phase)
+	annotation := ""
+	switch phase {
+	case ImageFlowPhaseDetect:
+		annotation = AnnotationImageFlowTemplateDefaultDetect
+	case ImageFlowPhaseCheck:
+		annotation = AnnotationImageFlowTemplateDefaultCheck
+	case ImageFlowPhaseUpload:
+		annotation = AnnotationImageFlowTemplateDefaultUpload
+	}
+	if ref != nil {
+		ret := *ref
+		if ret.Kind == "" {
+			ret.Kind = ImageFlowTemplateKindNamespaced
+		}
+		return ret
+	}
+	if name := r.Annotations[annotation]; name != "" {
+		return ImageFlowTemplateRef{Name: name, Kind: ImageFlowTemplateKindNamespaced}
+	}
+	return r.ResolvedTemplateRef()
+}
+
+// ImageFlowTemplate returns the cluster template as a template without namespace.
+func (r *ClusterImageFlowTemplate) ImageFlowTemplate() *ImageFlowTemplate {
+	return &ImageFlowTemplate{
+		TypeMeta:   r.TypeMeta,
+		ObjectMeta: *r.ObjectMeta.DeepCopy(),
+		Spec:       *r.Spec.DeepCopy(),
+		Status:     *r.Status.DeepCopy(),
+	}
+}
+
+// GetTemplate gets the template referenced by ref for an Image in namespace.
+// A namespaced template takes precedence over a ClusterImageFlowTemplate with the same name.
+// The returned template is merged with its base templates.
+func GetTemplate(ctx context.Context, c client.Reader, namespace string, ref ImageFlowTemplateRef) (*ImageFlowTemplate, error) {
+	return getTemplate(ctx, c, namespace, ref, map[types.NamespacedName]bool{})
+}
+
+func getTemplate(ctx context.Context, c client.Reader, namespace string, ref ImageFlowTemplateRef, visited map[types.NamespacedName]bool) (*ImageFlowTemplate, error) {
+	imt, err := fetchTemplate(ctx, c, namespace, ref, visited)
+	if err != nil {
+		return nil, err
+	}
+	key := types.NamespacedName{Name: imt.Name, Namespace: imt.Namespace}
+	if visited[key] {
+		return nil, fmt.Errorf("base template of %s is circular", key)
+	}
+	visited[key] = true
+	if imt.Spec.BaseImage == "" {
+		return imt, nil
+	}
+	// base of a namespaced template can be a ClusterImageFlowTemplate, but base of a cluster template must be cluster-scoped.
+	base, err := getTemplate(ctx, c, imt.Namespace, ImageFlowTemplateRef{Name: imt.Spec.BaseImage, Kind: ImageFlowTemplateKindCluster}, visited)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base template %s of %s: %w", imt.Spec.BaseImage, key, err)
+	}
+	spec, err := MergeSpec(base.Spec, imt.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge base template %s into %s: %w", imt.Spec.BaseImage, key, err)
+	}
+	imt.Spec = spec
+	return imt, nil
+}
+
+// fetchTemplate gets the template without merging base templates.
+// A namespaced template which is already visited is skipped so that a template can extend the cluster template with the same name.
+func fetchTemplate(ctx context.Context, c client.Reader, namespace string, ref ImageFlowTemplateRef, visited map[types.NamespacedName]bool) (*ImageFlowTemplate, error) {
+	key := types.NamespacedName{Name: ref.Name, Namespace: namespace}
+	if namespace != "" && !visited[key] {
+		imt := &ImageFlowTemplate{}
+		err := c.Get(ctx, key, imt)
+		if err == nil || !apierrors.IsNotFound(err) || ref.Kind != ImageFlowTemplateKindCluster {
+			return imt, err
+		}
+	}
+	cimt := &ClusterImageFlowTemplate{}
+	if err := c.Get(ctx, types.NamespacedName{Name: ref.Name}, cimt); err != nil {
+		return nil, err
+	}
+	return cimt.ImageFlowTemplate(), nil
+}
+
+// GetPhaseTemplates gets the template of each phase and composes them into one template.
+// The returned template has the metadata of the detect template.
+func GetPhaseTemplates(ctx context.Context, c client.Reader, image *Image) (*ImageFlowTemplate, error) {
+	templates := map[ImageFlowTemplateRef]*ImageFlowTemplate{}
+	ret := &ImageFlowTemplate{}
+	for _, phase := range ImageFlowPhases {
+		ref := image.PhaseTemplateRef(phase)
+		imt, ok := templates[ref]
+		if !ok {
+			var err error
+			imt, err = GetTemplate(ctx, c, image.Namespace, ref)
+			if err != nil {
+				return nil, err
+			}
+			templates[ref] = imt
+		}
+		switch phase {
+		case ImageFlowPhaseDetect:
+			ret.ObjectMeta = *imt.ObjectMeta.DeepCopy()
+			ret.Spec.BaseImage = imt.Spec.BaseImage
+			ret.Spec.Detect = *imt.Spec.Detect.DeepCopy()
+		case ImageFlowPhaseCheck:
+			ret.Spec.Check = *imt.Spec.Check.DeepCopy()
+		case ImageFlowPhaseUpload:
+			ret.Spec.Upload = *imt.Spec.Upload.DeepCopy()
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,233 @@
+package v1beta1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetTemplate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	namespaced := &ContainerApplyConfiguration{Image: pointer.String("namespaced")}
+	cluster := &ContainerApplyConfiguration{Image: pointer.String("cluster")}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&ImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"},
+			Spec:       ImageFlowTemplateSpec{Detect: ImageFlowTemplateSpecTemplate{Actor: namespaced}},
+		},
+		&ClusterImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+			Spec:       ImageFlowTemplateSpec{Detect: ImageFlowTemplateSpecTemplate{Actor: cluster}},
+		},
+	).Build()
+	tests := []struct {
+		name      string
+		namespace string
+		ref       ImageFlowTemplateRef
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "namespaced",
+			namespace: "default",
+			ref:       ImageFlowTemplateRef{Name: "shared", Kind: ImageFlowTemplateKindNamespaced},
+			want:      "namespaced",
+		},
+		{
+			name:      "namespaced_takes_precedence",
+			namespace: "default",
+			ref:       ImageFlowTemplateRef{Name: "shared", Kind: ImageFlowTemplateKindCluster},
+			want:      "namespaced",
+		},
+		{
+			name:      "cluster",
+			namespace: "other",
+			ref:       ImageFlowTemplateRef{Name: "shared", Kind: ImageFlowTemplateKindCluster},
+			want:      "cluster",
+		},
+		{
+			name:      "namespaced_not_found",
+			namespace: "other",
+			ref:       ImageFlowTemplateRef{Name: "shared", Kind: ImageFlowTemplateKindNamespaced},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetTemplate(context.Background(), c, tt.namespace, tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got.Spec.Detect.Actor.Image != tt.want {
+				t.Errorf("GetTemplate() = %s, want %s", *got.Spec.Detect.Actor.Image, tt.want)
+			}
+		})
+	}
+}
+
+func TestImage_ResolvedTemplateRef(t *testing.T) {
+	tests := []struct {
+		name  string
+		image Image
+		want  ImageFlowTemplateRef
+	}{
+		{
+			name:  "template_name",
+			image: Image{Spec: ImageSpec{TemplateName: "test"}},
+			want:  ImageFlowTemplateRef{Name: "test", Kind: ImageFlowTemplateKindNamespaced},
+		},
+		{
+			name: "annotation",
+			image: Image{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{AnnotationImageFlowTemplateDefaultAll: "default"},
+			}},
+			want: ImageFlowTemplateRef{Name: "default", Kind: ImageFlowTemplateKindNamespaced},
+		},
+		{
+			name:  "template_ref",
+			image: Image{Spec: ImageSpec{TemplateRef: &ImageFlowTemplateRef{Name: "test", Kind: ImageFlowTemplateKindCluster}}},
+			want:  ImageFlowTemplateRef{Name: "test", Kind: ImageFlowTemplateKindCluster},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.image.ResolvedTemplateRef(); got != tt.want {
+				t.Errorf("ResolvedTemplateRef() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPhaseTemplates(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	actor := func(image string) ImageFlowTemplateSpecTemplate {
+		return ImageFlowTemplateSpecTemplate{Actor: &ContainerApplyConfiguration{Image: pointer.String(image)}}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&ImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "github", Namespace: "default"},
+			Spec:       ImageFlowTemplateSpec{Detect: actor("github-detect"), Check: actor("github-check"), Upload: actor("github-upload")},
+		},
+		&ImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "buildkit", Namespace: "default"},
+			Spec:       ImageFlowTemplateSpec{Upload: actor("buildkit-upload")},
+		},
+		&ClusterImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "registryv2"},
+			Spec:       ImageFlowTemplateSpec{Check: actor("registryv2-check")},
+		},
+	).Build()
+	image := &Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationImageFlowTemplateDefaultUpload: "buildkit"},
+		},
+		Spec: ImageSpec{
+			TemplateName: "github",
+			PhaseTemplateRefs: &ImagePhaseTemplateRefs{
+				Check: &ImageFlowTemplateRef{Name: "registryv2", Kind: ImageFlowTemplateKindCluster},
+			},
+		},
+	}
+	got, err := GetPhaseTemplates(context.Background(), c, image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "github" {
+		t.Errorf("name = %s, want github", got.Name)
+	}
+	if got.Spec.Detect.Actor == nil || got.Spec.Check.Actor == nil || got.Spec.Upload.Actor == nil {
+		t.Fatalf("actors should be filled: %v", got.Spec)
+	}
+	if *got.Spec.Detect.Actor.Image != "github-detect" || *got.Spec.Check.Actor.Image != "registryv2-check" || *got.Spec.Upload.Actor.Image != "buildkit-upload" {
+		t.Errorf("actors = %s, %s, %s", *got.Spec.Detect.Actor.Image, *got.Spec.Check.Actor.Image, *got.Spec.Upload.Actor.Image)
+	}
+	image.Spec.TemplateName = ""
+	if _, err := GetPhaseTemplates(context.Background(), c, image); err == nil {
+		t.Errorf("GetPhaseTemplates() should fail without detect template")
+	}
+}
+
+func TestGetTemplate_BaseImage(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	actor := func(image string) ImageFlowTemplateSpecTemplate {
+		return ImageFlowTemplateSpecTemplate{Actor: &ContainerApplyConfiguration{Image: pointer.String(image)}}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&ClusterImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "github"},
+			Spec:       ImageFlowTemplateSpec{Detect: actor("github-detect"), Check: actor("github-check"), Upload: actor("github-upload")},
+		},
+		&ImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "github", Namespace: "default"},
+			Spec:       ImageFlowTemplateSpec{BaseImage: "github", Upload: actor("child-upload")},
+		},
+		&ImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "grandchild", Namespace: "default"},
+			Spec:       ImageFlowTemplateSpec{BaseImage: "github", Check: actor("grandchild-check")},
+		},
+		&ClusterImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "a"},
+			Spec:       ImageFlowTemplateSpec{BaseImage: "b"},
+		},
+		&ClusterImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "b"},
+			Spec:       ImageFlowTemplateSpec{BaseImage: "a"},
+		},
+	).Build()
+	tests := []struct {
+		name    string
+		ref     ImageFlowTemplateRef
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "extend_cluster_template_with_same_name",
+			ref:  ImageFlowTemplateRef{Name: "github", Kind: ImageFlowTemplateKindNamespaced},
+			want: []string{"github-detect", "github-check", "child-upload"},
+		},
+		{
+			name: "extend_namespaced_template",
+			ref:  ImageFlowTemplateRef{Name: "grandchild", Kind: ImageFlowTemplateKindNamespaced},
+			want: []string{"github-detect", "grandchild-check", "child-upload"},
+		},
+		{
+			name:    "circular",
+			ref:     ImageFlowTemplateRef{Name: "a", Kind: ImageFlowTemplateKindCluster},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetTemplate(context.Background(), c, "default", tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			actors := []string{*got.Spec.Detect.Actor.Image, *got.Spec.Check.Actor.Image, *got.Spec.Upload.Actor.Image}
+			if diff := cmp.Diff(tt.want, actors); diff != "" {
+				t.Errorf("GetTemplate() diff: %s", diff)
+			}
+		})
+	}
+}
@@ -23,9 +23,69 @@ package v1beta1
 
 import (
 	"k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterImageFlowTemplate) DeepCopyInto(out *ClusterImageFlowTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterImageFlowTemplate.
+func (in *ClusterImageFlowTemplate) DeepCopy() *ClusterImageFlowTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterImageFlowTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterImageFlowTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterImageFlowTemplateList) DeepCopyInto(out *ClusterImageFlowTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterImageFlowTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterImageFlowTemplateList.
+func (in *ClusterImageFlowTemplateList) DeepCopy() *ClusterImageFlowTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterImageFlowTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterImageFlowTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ContainerApplyConfiguration) DeepCopyInto(out *ContainerApplyConfiguration) {
 	clone := in.DeepCopy()
@@ -99,7 +159,7 @@ func (in *ImageFlowTemplate) DeepCopyInto(out *ImageFlowTemplate) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageFlowTemplate.
@@ -120,6 +180,21 @@ func (in *ImageFlowTemplate) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageFlowTemplateAuthEnv) DeepCopyInto(out *ImageFlowTemplateAuthEnv) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageFlowTemplateAuthEnv.
+func (in *ImageFlowTemplateAuthEnv) DeepCopy() *ImageFlowTemplateAuthEnv {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageFlowTemplateAuthEnv)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageFlowTemplateList) DeepCopyInto(out *ImageFlowTemplateList) {
 	*out = *in
@@ -152,6 +227,21 @@ func (in *ImageFlowTemplateList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageFlowTemplateRef) DeepCopyInto(out *ImageFlowTemplateRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageFlowTemplateRef.
+func (in *ImageFlowTemplateRef) DeepCopy() *ImageFlowTemplateRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageFlowTemplateRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageFlowTemplateSpec) DeepCopyInto(out *ImageFlowTemplateSpec) {
 	*out = *in
@@ -189,6 +279,59 @@ func (in *ImageFlowTemplateSpecTemplate) DeepCopyInto(out *ImageFlowTemplateSpec
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.InitContainers != nil {
+		in, out := &in.InitContainers, &out.InitContainers
+		*out = make([]ContainerApplyConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]ContainerApplyConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSecurityContext != nil {
+		in, out := &in.PodSecurityContext, &out.PodSecurityContext
+		*out = new(v1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AuthEnv != nil {
+		in, out := &in.AuthEnv, &out.AuthEnv
+		*out = make([]ImageFlowTemplateAuthEnv, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageFlowTemplateSpecTemplate.
@@ -204,6 +347,18 @@ func (in *ImageFlowTemplateSpecTemplate) DeepCopy() *ImageFlowTemplateSpecTempla
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageFlowTemplateStatus) DeepCopyInto(out *ImageFlowTemplateStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageFlowTemplateStatus.
@@ -216,6 +371,25 @@ func (in *ImageFlowTemplateStatus) DeepCopy() *ImageFlowTemplateStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageLatestStatus) DeepCopyInto(out *ImageLatestStatus) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageLatestStatus.
+func (in *ImageLatestStatus) DeepCopy() *ImageLatestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageLatestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageList) DeepCopyInto(out *ImageList) {
 	*out = *in
@@ -248,6 +422,36 @@ func (in *ImageList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePhaseTemplateRefs) DeepCopyInto(out *ImagePhaseTemplateRefs) {
+	*out = *in
+	if in.Detect != nil {
+		in, out := &in.Detect, &out.Detect
+		*out = new(ImageFlowTemplateRef)
+		**out = **in
+	}
+	if in.Check != nil {
+		in, out := &in.Check, &out.Check
+		*out = new(ImageFlowTemplateRef)
+		**out = **in
+	}
+	if in.Upload != nil {
+		in, out := &in.Upload, &out.Upload
+		*out = new(ImageFlowTemplateRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePhaseTemplateRefs.
+func (in *ImagePhaseTemplateRefs) DeepCopy() *ImagePhaseTemplateRefs {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePhaseTemplateRefs)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageRepository) DeepCopyInto(out *ImageRepository) {
 	*out = *in
@@ -255,7 +459,9 @@ func (in *ImageRepository) DeepCopyInto(out *ImageRepository) {
 	if in.TagPolicies != nil {
 		in, out := &in.TagPolicies, &out.TagPolicies
 		*out = make([]ImageTagPolicy, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -269,14 +475,46 @@ func (in *ImageRepository) DeepCopy() *ImageRepository {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageRetryPolicy) DeepCopyInto(out *ImageRetryPolicy) {
+	*out = *in
+	if in.Backoff != nil {
+		in, out := &in.Backoff, &out.Backoff
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageRetryPolicy.
+func (in *ImageRetryPolicy) DeepCopy() *ImageRetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageRetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageSpec) DeepCopyInto(out *ImageSpec) {
 	*out = *in
+	if in.TemplateRef != nil {
+		in, out := &in.TemplateRef, &out.TemplateRef
+		*out = new(ImageFlowTemplateRef)
+		**out = **in
+	}
+	if in.PhaseTemplateRefs != nil {
+		in, out := &in.PhaseTemplateRefs, &out.PhaseTemplateRefs
+		*out = new(ImagePhaseTemplateRefs)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Repository.DeepCopyInto(&out.Repository)
 	if in.Targets != nil {
 		in, out := &in.Targets, &out.Targets
 		*out = make([]ImageTarget, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
@@ -285,6 +523,11 @@ func (in *ImageSpec) DeepCopyInto(out *ImageSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(ImageRetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSpec.
@@ -307,6 +550,17 @@ func (in *ImageStatus) DeepCopyInto(out *ImageStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Latest != nil {
+		in, out := &in.Latest, &out.Latest
+		*out = make([]ImageLatestStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastDetectTime != nil {
+		in, out := &in.LastDetectTime, &out.LastDetectTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatus.
@@ -322,6 +576,11 @@ func (in *ImageStatus) DeepCopy() *ImageStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageTagPolicy) DeepCopyInto(out *ImageTagPolicy) {
 	*out = *in
+	if in.Semver != nil {
+		in, out := &in.Semver, &out.Semver
+		*out = new(ImageTagPolicySemver)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageTagPolicy.
@@ -334,10 +593,32 @@ func (in *ImageTagPolicy) DeepCopy() *ImageTagPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageTagPolicySemver) DeepCopyInto(out *ImageTagPolicySemver) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageTagPolicySemver.
+func (in *ImageTagPolicySemver) DeepCopy() *ImageTagPolicySemver {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageTagPolicySemver)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageTarget) DeepCopyInto(out *ImageTarget) {
 	*out = *in
 	out.Auth = in.Auth
+	if in.BuildArgs != nil {
+		in, out := &in.BuildArgs, &out.BuildArgs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageTarget.
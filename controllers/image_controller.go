@@ -18,7 +18,9 @@ package controllers
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -26,10 +28,15 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/sirupsen/logrus"
 	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
@@ -39,17 +46,28 @@ import (
 // ImageReconciler reconciles a Image object
 type ImageReconciler struct {
 	client.Client
-	Scheme   *runtime.Scheme
-	Recorder record.EventRecorder
+	// Clientset reads pods and logs of failed actors, which are not cached.
+	Clientset kubernetes.Interface
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	// Actor configures where actor workloads run.
+	Actor imageutil.Options
+	// RateLimiter backs off Images which fail with transient errors. The default of controller-runtime is used when it is nil.
+	RateLimiter ratelimiter.RateLimiter
 }
 
 //+kubebuilder:rbac:groups=build.takutakahashi.dev,resources=images,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=build.takutakahashi.dev,resources=imageflowtemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=build.takutakahashi.dev,resources=clusterimageflowtemplates,verbs=get;list;watch
 //+kubebuilder:rbac:groups=build.takutakahashi.dev,resources=images/status,verbs=list;get;create;update;patch;watch
 //+kubebuilder:rbac:groups=build.takutakahashi.dev,resources=images/finalizers,verbs=update;watch
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=list;get;create;update;patch;delete;watch
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=list;get;create;update;patch;delete;watch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=list;get;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=list;get
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=list;get;create;update;patch;delete;watch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=list;get;create;update;patch;delete;watch
 
 const IMAGE_FINALIZERS string = "build.takutakahashi.dev/image"
 
@@ -57,11 +75,18 @@ func (r *ImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	logger := log.FromContext(ctx)
 	image, imt, secrets, err := r.gatherResources(ctx, req)
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if errors.IsNotFound(err) && image == nil {
 			return ctrl.Result{}, nil
 		}
+		var terr *imageutil.TemplateError
+		if stderrors.As(err, &terr) {
+			return r.updateTemplateCondition(ctx, image, terr)
+		}
+		if perr, ok := imageutil.AsPermanentError(err); ok && image != nil {
+			return r.updateReconciledCondition(ctx, image, image, perr)
+		}
 		logger.Error(err, "failed to gather required resources")
-		return ctrl.Result{Requeue: true}, nil
+		return ctrl.Result{}, err
 	}
 	logrus.Info(image.GetFinalizers())
 	if image.GetFinalizers() == nil {
@@ -69,12 +94,43 @@ func (r *ImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		updated.SetFinalizers([]string{IMAGE_FINALIZERS})
 		return ctrl.Result{}, r.Update(ctx, updated, &client.UpdateOptions{})
 	}
-	after, err := imageutil.Ensure(ctx, r.Client, image.DeepCopy(), imt, secrets)
-	if err != nil {
+	if image.DeletionTimestamp == nil {
+		if err := imageutil.ValidateTemplate(image, imt); err != nil {
+			return r.updateTemplateCondition(ctx, image, err)
+		}
+	}
+	before := image.DeepCopy()
+	var retryAfter time.Duration
+	if image.DeletionTimestamp == nil {
+		image.Status.Conditions = imageutil.UpdateTemplateCondition(image.Status.Conditions, nil)
+		image.Status.Conditions = imageutil.UpdateReconciledCondition(image.Status.Conditions, nil)
+		if image, err = imageutil.UpdateJobConditions(ctx, r.Client, r.Clientset, image, r.Actor); err != nil {
+			logger.Error(err, "failed to update job conditions")
+			return ctrl.Result{}, err
+		}
+		retryAfter = imageutil.RetryUploads(image, time.Now())
+	}
+	after, err := imageutil.Ensure(ctx, r.Client, image.DeepCopy(), imt, secrets, r.Actor)
+	var werr *imageutil.WaitingError
+	if stderrors.As(err, &werr) {
+		logrus.Infof("waiting: %s", werr.Message)
+		if retryAfter == 0 || werr.After < retryAfter {
+			retryAfter = werr.After
+		}
+	} else if perr, ok := imageutil.AsPermanentError(err); ok {
+		return r.updateReconciledCondition(ctx, before, image, perr)
+	} else if err != nil {
 		logger.Error(err, "failed to ensure image")
-		return ctrl.Result{Requeue: true}, nil
+		return ctrl.Result{}, err
 	}
-	diff := imageutil.Diff(image, after)
+	if after.DeletionTimestamp == nil {
+		if after, err = imageutil.UpdateDetectorCondition(ctx, r.Client, r.Clientset, after, r.Actor); err != nil {
+			logger.Error(err, "failed to update detector condition")
+			return ctrl.Result{}, err
+		}
+		imageutil.UpdateSummary(after)
+	}
+	diff := imageutil.Diff(before, after)
 	if diff != "" {
 		logrus.Infof("diff: %s", diff)
 		if err := r.Status().Update(ctx, after, &client.UpdateOptions{}); err != nil {
@@ -84,15 +140,66 @@ func (r *ImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	//conds := imageutil.ReapStaleConditions(after.Status.Conditions)
 	logrus.Info("no diff detected")
 	logrus.Info("reconcilation finished")
+	if after.DeletionTimestamp == nil {
+		// polls update the image, so it is reconciled again to find the stale detector when they stop
+		requeueAfter := r.Actor.DetectStaleAfter()
+		if retryAfter > 0 && retryAfter < requeueAfter {
+			requeueAfter = retryAfter
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// updateTemplateCondition marks the Image as not ready without creating workloads.
+// Templates are watched, so the Image is not requeued until the template is fixed.
+func (r *ImageReconciler) updateTemplateCondition(ctx context.Context, image *buildv1beta1.Image, err error) (ctrl.Result, error) {
+	r.Recorder.Event(image, corev1.EventTypeWarning, "TemplateNotReady", err.Error())
+	after := image.DeepCopy()
+	after.Status.Conditions = imageutil.UpdateTemplateCondition(after.Status.Conditions, err)
+	return r.updateBlockedStatus(ctx, image, after)
+}
+
+// updateReconciledCondition marks the Image as failed by the permanent error, keeping the other changes of image from before.
+// The Image and its secrets are watched, so the Image is not requeued until they change.
+func (r *ImageReconciler) updateReconciledCondition(ctx context.Context, before, image *buildv1beta1.Image, err *imageutil.PermanentError) (ctrl.Result, error) {
+	r.Recorder.Event(image, corev1.EventTypeWarning, err.Reason, err.Message)
+	after := image.DeepCopy()
+	after.Status.Conditions = imageutil.UpdateReconciledCondition(after.Status.Conditions, err)
+	return r.updateBlockedStatus(ctx, before, after)
+}
+
+func (r *ImageReconciler) updateBlockedStatus(ctx context.Context, before, after *buildv1beta1.Image) (ctrl.Result, error) {
+	imageutil.UpdateSummary(after)
+	if imageutil.Diff(before, after) != "" {
+		if err := r.Status().Update(ctx, after, &client.UpdateOptions{}); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// Images are reconciled again when the templates or the auth secrets which they depend on change,
+// and when their actor workloads change even if they run in the other namespace.
 func (r *ImageReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ctx := context.Background()
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &buildv1beta1.Image{}, imageTemplateNameIndex, imageTemplateNames); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &buildv1beta1.Image{}, imageAuthSecretIndex, imageAuthSecrets); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{RateLimiter: r.RateLimiter}).
 		For(&buildv1beta1.Image{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&batchv1.Job{}).
+		Watches(&source.Kind{Type: &buildv1beta1.ImageFlowTemplate{}}, handler.EnqueueRequestsFromMapFunc(r.imagesForTemplate)).
+		Watches(&source.Kind{Type: &buildv1beta1.ClusterImageFlowTemplate{}}, handler.EnqueueRequestsFromMapFunc(r.imagesForClusterTemplate)).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.imagesForSecret)).
+		Watches(&source.Kind{Type: &appsv1.Deployment{}}, handler.EnqueueRequestsFromMapFunc(imageForActor)).
+		Watches(&source.Kind{Type: &batchv1.Job{}}, handler.EnqueueRequestsFromMapFunc(imageForActor)).
 		Complete(r)
 }
 
@@ -102,32 +209,51 @@ func (r *ImageReconciler) gatherResources(ctx context.Context, req ctrl.Request)
 	if err := r.Get(ctx, req.NamespacedName, image); err != nil {
 		return nil, nil, nil, err
 	}
-	imt := &buildv1beta1.ImageFlowTemplate{}
-	imtName := image.Spec.TemplateName
-	if imtName == "" {
-		imtName = image.Annotations[buildv1beta1.AnnotationImageFlowTemplateDefaultAll]
-		r.Recorder.Eventf(image, corev1.EventTypeNormal, "UseDefaultTemplate", "use default template: %s", imtName)
+	if name := image.ResolvedTemplateRef().Name; name != "" && image.Spec.TemplateName == "" && image.Spec.TemplateRef == nil {
+		r.Recorder.Eventf(image, corev1.EventTypeNormal, "UseDefaultTemplate", "use default template: %s", name)
 	}
-	if err := r.Get(ctx, types.NamespacedName{Name: imtName, Namespace: image.Namespace}, imt); err != nil {
-		r.Recorder.Event(image, corev1.EventTypeWarning, "TemplateNotFound", err.Error())
-		return nil, nil, nil, err
+	imt, err := buildv1beta1.GetPhaseTemplates(ctx, r.Client, image)
+	if err != nil {
+		if image.DeletionTimestamp != nil {
+			// workloads can be deleted without template
+			imt = &buildv1beta1.ImageFlowTemplate{}
+		} else if errors.IsNotFound(err) {
+			return image, nil, nil, &imageutil.TemplateError{Reason: imageutil.ReasonTemplateNotFound, Message: err.Error()}
+		} else if status := errors.APIStatus(nil); !stderrors.As(err, &status) {
+			// base templates are circular or can not be merged
+			return image, nil, nil, &imageutil.TemplateError{Reason: imageutil.ReasonTemplateInvalid, Message: err.Error()}
+		} else {
+			return image, nil, nil, err
+		}
 	}
 	secrets := map[string]*corev1.Secret{}
-	if image.Spec.Repository.Auth.SecretName != "" {
-		s := &corev1.Secret{}
-		if err := r.Get(ctx, types.NamespacedName{Name: image.Spec.Repository.Auth.SecretName, Namespace: image.Namespace}, s); err == nil {
-			secrets[fmt.Sprintf("repository/%s", image.Spec.Repository.Auth.SecretName)] = s
+	if name := image.Spec.Repository.Auth.SecretName; name != "" {
+		if err := r.getAuthSecret(ctx, image, name, fmt.Sprintf("repository/%s", name), secrets); err != nil {
+			return image, nil, nil, err
 		}
 	}
 	for _, target := range image.Spec.Targets {
-		if target.Auth.SecretName == "" {
-			continue
-		}
-		s := &corev1.Secret{}
-		if err := r.Get(ctx, types.NamespacedName{Name: target.Auth.SecretName, Namespace: image.Namespace}, s); err != nil {
-			return nil, nil, nil, err
+		if name := target.Auth.SecretName; name != "" {
+			if err := r.getAuthSecret(ctx, image, name, fmt.Sprintf("targets/%s", name), secrets); err != nil {
+				return image, nil, nil, err
+			}
 		}
-		secrets[fmt.Sprintf("targets/%s", target.Auth.SecretName)] = s
 	}
 	return image, imt, secrets, nil
 }
+
+// getAuthSecret stores the auth secret of the image in secrets by key.
+// A missing secret is a permanent error since secrets are watched, except that workloads can be deleted without it.
+func (r *ImageReconciler) getAuthSecret(ctx context.Context, image *buildv1beta1.Image, name, key string, secrets map[string]*corev1.Secret) error {
+	s := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: image.Namespace}, s); errors.IsNotFound(err) {
+		if image.DeletionTimestamp != nil {
+			return nil
+		}
+		return &imageutil.PermanentError{Reason: imageutil.ReasonSecretNotFound, Message: err.Error()}
+	} else if err != nil {
+		return err
+	}
+	secrets[key] = s
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
@@ -12,15 +13,19 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	imageutil "github.com/takutakahashi/oci-image-operator/pkg/image"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/pointer"
 	"k8s.io/utils/strings/slices"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 var _ = Describe("Image controller", func() {
@@ -139,16 +144,19 @@ var _ = Describe("Image controller", func() {
 			}).WithTimeout(2000 * time.Millisecond).Should(Succeed())
 			Eventually(func() error {
 				job := batchv1.Job{}
-				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "test-check-check-9ac55c1", Namespace: "oci-image-operator-system"}, &job); err != nil {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "test-check-check-d2ffe19", Namespace: "oci-image-operator-system"}, &job); err != nil {
 					return err
 				}
-				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "test-check-check-7c68eaf", Namespace: "oci-image-operator-system"}, &job); err != nil {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "test-check-check-43c6d38", Namespace: "oci-image-operator-system"}, &job); err != nil {
 					return err
 				}
 				c := mainContainer(job.Spec.Template.Spec.Containers)
 				if e := getEnv(c.Env, "RESOLVED_REVISION"); e.Value != "test12345" {
 					return fmt.Errorf("env is not match. env: %v", e)
 				}
+				if e := getEnv(c.Env, "IMAGE_TARGET"); e.Value != "ghcr.io/takutakahashi/test" {
+					return fmt.Errorf("env is not match. env: %v", e)
+				}
 
 				if e := getEnv(c.Env, "DEF_IMAGE_VALUE"); e.Value != "image" {
 					return fmt.Errorf("target branches = %v", e.Value)
@@ -191,10 +199,10 @@ var _ = Describe("Image controller", func() {
 					return fmt.Errorf("conditions are not found")
 				}
 				job := batchv1.Job{}
-				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "test-upload-upload-7c68eaf", Namespace: "oci-image-operator-system"}, &job); err != nil {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "test-upload-upload-43c6d38", Namespace: "oci-image-operator-system"}, &job); err != nil {
 					return err
 				}
-				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "test-upload-upload-9ac55c1", Namespace: "oci-image-operator-system"}, &job); err != nil {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: "test-upload-upload-d2ffe19", Namespace: "oci-image-operator-system"}, &job); err != nil {
 					return err
 				}
 				return nil
@@ -413,3 +421,28 @@ func finalizerSet(c client.Client, objKey types.NamespacedName, obj client.Objec
 		return nil
 	}
 }
+
+func TestGetAuthSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	r := &ImageReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(newSecret("github")).Build()}
+	image := &buildv1beta1.Image{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	secrets := map[string]*corev1.Secret{}
+	if err := r.getAuthSecret(context.Background(), image, "github", "repository/github", secrets); err != nil || secrets["repository/github"] == nil {
+		t.Errorf("getAuthSecret() = %v, secrets = %v", err, secrets)
+	}
+	err := r.getAuthSecret(context.Background(), image, "missing", "repository/missing", secrets)
+	if perr, ok := imageutil.AsPermanentError(err); !ok || perr.Reason != imageutil.ReasonSecretNotFound {
+		t.Errorf("getAuthSecret() = %v, want permanent error", err)
+	}
+	now := metav1.Now()
+	image.DeletionTimestamp = &now
+	if err := r.getAuthSecret(context.Background(), image, "missing", "repository/missing", secrets); err != nil {
+		t.Errorf("getAuthSecret() of deleting image = %v", err)
+	}
+	if _, ok := secrets["repository/missing"]; ok {
+		t.Errorf("missing secret is stored")
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	imageutil "github.com/takutakahashi/oci-image-operator/pkg/image"
+)
+
+const (
+	// imageTemplateNameIndex indexes Images by the names of the templates which their phases refer.
+	// Names are indexed without kind since a namespaced template shadows the cluster template with the same name.
+	imageTemplateNameIndex = ".spec.templateName"
+	// imageAuthSecretIndex indexes Images by the names of the auth secrets of the repository and targets.
+	imageAuthSecretIndex = ".spec.auth.secretName"
+)
+
+// imageTemplateNames returns the names of the templates which the phases of the image refer.
+func imageTemplateNames(obj client.Object) []string {
+	image, ok := obj.(*buildv1beta1.Image)
+	if !ok {
+		return nil
+	}
+	return uniqueNames(func(add func(string)) {
+		for _, phase := range buildv1beta1.ImageFlowPhases {
+			add(image.PhaseTemplateRef(phase).Name)
+		}
+	})
+}
+
+// imageAuthSecrets returns the names of the auth secrets of the image.
+func imageAuthSecrets(obj client.Object) []string {
+	image, ok := obj.(*buildv1beta1.Image)
+	if !ok {
+		return nil
+	}
+	return uniqueNames(func(add func(string)) {
+		add(image.Spec.Repository.Auth.SecretName)
+		for _, target := range image.Spec.Targets {
+			add(target.Auth.SecretName)
+		}
+	})
+}
+
+func uniqueNames(f func(add func(string))) []string {
+	ret := []string{}
+	seen := map[string]bool{}
+	f(func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			ret = append(ret, name)
+		}
+	})
+	return ret
+}
+
+// dependentTemplateNames returns the name and the names of the templates which extend it directly or indirectly.
+// bases maps names of templates to their base template.
+func dependentTemplateNames(name string, bases ...map[string]string) []string {
+	children := map[string][]string{}
+	for _, b := range bases {
+		for child, base := range b {
+			if child != base {
+				children[base] = append(children[base], child)
+			}
+		}
+	}
+	return uniqueNames(func(add func(string)) {
+		queue := []string{name}
+		seen := map[string]bool{}
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			add(n)
+			queue = append(queue, children[n]...)
+		}
+	})
+}
+
+// imagesForTemplate maps an ImageFlowTemplate to the Images in its namespace which depend on it.
+func (r *ImageReconciler) imagesForTemplate(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	namespaced, cluster, err := templateBases(ctx, r.Client, obj.GetNamespace())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list templates", "template", obj.GetName())
+		return nil
+	}
+	return r.imageRequests(ctx, imageTemplateNameIndex, dependentTemplateNames(obj.GetName(), cluster, namespaced[obj.GetNamespace()]), client.InNamespace(obj.GetNamespace()))
+}
+
+// imagesForClusterTemplate maps a ClusterImageFlowTemplate to the Images in all namespaces which depend on it.
+// Namespaced templates which extend it are considered in each namespace.
+func (r *ImageReconciler) imagesForClusterTemplate(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	namespaced, cluster, err := templateBases(ctx, r.Client, "")
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list templates", "template", obj.GetName())
+		return nil
+	}
+	ret := r.imageRequests(ctx, imageTemplateNameIndex, dependentTemplateNames(obj.GetName(), cluster))
+	for namespace, bases := range namespaced {
+		ret = append(ret, r.imageRequests(ctx, imageTemplateNameIndex, dependentTemplateNames(obj.GetName(), cluster, bases), client.InNamespace(namespace))...)
+	}
+	return uniqueRequests(ret)
+}
+
+// imagesForSecret maps a Secret to the Images in its namespace which use it as auth.
+func (r *ImageReconciler) imagesForSecret(obj client.Object) []reconcile.Request {
+	if _, ok := obj.(*corev1.Secret); !ok {
+		return nil
+	}
+	return r.imageRequests(context.Background(), imageAuthSecretIndex, []string{obj.GetName()}, client.InNamespace(obj.GetNamespace()))
+}
+
+// imageForActor maps an actor Deployment or Job to its Image by labels.
+func imageForActor(obj client.Object) []reconcile.Request {
+	name, namespace := obj.GetLabels()[imageutil.ImageLabel], obj.GetLabels()[imageutil.ImageNamespaceLabel]
+	if name == "" || namespace == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
+}
+
+// templateBases returns base names of the namespaced templates by namespace and of the cluster templates.
+// All namespaces are listed when namespace is empty.
+func templateBases(ctx context.Context, c client.Reader, namespace string) (map[string]map[string]string, map[string]string, error) {
+	templates := &buildv1beta1.ImageFlowTemplateList{}
+	if err := c.List(ctx, templates, client.InNamespace(namespace)); err != nil {
+		return nil, nil, err
+	}
+	clusterTemplates := &buildv1beta1.ClusterImageFlowTemplateList{}
+	if err := c.List(ctx, clusterTemplates); err != nil {
+		return nil, nil, err
+	}
+	namespaced := map[string]map[string]string{}
+	for _, imt := range templates.Items {
+		if imt.Spec.BaseImage == "" {
+			continue
+		}
+		if namespaced[imt.Namespace] == nil {
+			namespaced[imt.Namespace] = map[string]string{}
+		}
+		namespaced[imt.Namespace][imt.Name] = imt.Spec.BaseImage
+	}
+	cluster := map[string]string{}
+	for _, cimt := range clusterTemplates.Items {
+		if cimt.Spec.BaseImage != "" {
+			cluster[cimt.Name] = cimt.Spec.BaseImage
+		}
+	}
+	return namespaced, cluster, nil
+}
+
+// imageRequests returns requests of the Images which have any of the values in the index.
+func (r *ImageReconciler) imageRequests(ctx context.Context, index string, values []string, opts ...client.ListOption) []reconcile.Request {
+	ret := []reconcile.Request{}
+	for _, value := range values {
+		images := &buildv1beta1.ImageList{}
+		if err := r.List(ctx, images, append(opts, client.MatchingFields{index: value})...); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list images", "index", index, "value", value)
+			continue
+		}
+		for _, image := range images.Items {
+			ret = append(ret, reconcile.Request{NamespacedName: types.NamespacedName{Name: image.Name, Namespace: image.Namespace}})
+		}
+	}
+	return uniqueRequests(ret)
+}
+
+func uniqueRequests(reqs []reconcile.Request) []reconcile.Request {
+	ret := []reconcile.Request{}
+	seen := map[reconcile.Request]bool{}
+	for _, req := range reqs {
+		if !seen[req] {
+			seen[req] = true
+			ret = append(ret, req)
+		}
+	}
+	return ret
+}
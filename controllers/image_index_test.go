@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	imageutil "github.com/takutakahashi/oci-image-operator/pkg/image"
+)
+
+func TestImageIndex(t *testing.T) {
+	image := &buildv1beta1.Image{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "default",
+			Annotations: map[string]string{buildv1beta1.AnnotationImageFlowTemplateDefaultUpload: "upload"},
+		},
+		Spec: buildv1beta1.ImageSpec{
+			TemplateRef: &buildv1beta1.ImageFlowTemplateRef{Name: "all", Kind: buildv1beta1.ImageFlowTemplateKindCluster},
+			PhaseTemplateRefs: &buildv1beta1.ImagePhaseTemplateRefs{
+				Detect: &buildv1beta1.ImageFlowTemplateRef{Name: "detect"},
+			},
+			Repository: buildv1beta1.ImageRepository{Auth: buildv1beta1.ImageAuth{SecretName: "github"}},
+			Targets: []buildv1beta1.ImageTarget{
+				{Name: "ghcr.io/takutakahashi/a", Auth: buildv1beta1.ImageAuth{SecretName: "ghcr"}},
+				{Name: "ghcr.io/takutakahashi/b", Auth: buildv1beta1.ImageAuth{SecretName: "ghcr"}},
+				{Name: "docker.io/takutakahashi/c"},
+			},
+		},
+	}
+	if diff := cmp.Diff([]string{"detect", "all", "upload"}, imageTemplateNames(image)); diff != "" {
+		t.Errorf("imageTemplateNames() diff: %s", diff)
+	}
+	if diff := cmp.Diff([]string{"github", "ghcr"}, imageAuthSecrets(image)); diff != "" {
+		t.Errorf("imageAuthSecrets() diff: %s", diff)
+	}
+}
+
+func TestImageForActor(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   []reconcile.Request
+	}{
+		{
+			name:   "labeled",
+			labels: map[string]string{imageutil.ImageLabel: "test", imageutil.ImageNamespaceLabel: "default"},
+			want:   []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "test", Namespace: "default"}}},
+		},
+		{
+			name:   "without_namespace",
+			labels: map[string]string{imageutil.ImageLabel: "test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "test-check-0123456", Namespace: "oci-image-operator-system", Labels: tt.labels}}
+			if diff := cmp.Diff(tt.want, imageForActor(job)); diff != "" {
+				t.Errorf("imageForActor() diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestDependentTemplateNames(t *testing.T) {
+	cluster := map[string]string{"child": "base", "grandchild": "child", "self": "self"}
+	namespaced := map[string]string{"self": "self", "team": "grandchild", "other": "unrelated"}
+	tests := []struct {
+		name     string
+		template string
+		bases    []map[string]string
+		want     []string
+	}{
+		{
+			name:     "cluster",
+			template: "base",
+			bases:    []map[string]string{cluster},
+			want:     []string{"base", "child", "grandchild"},
+		},
+		{
+			name:     "namespaced",
+			template: "base",
+			bases:    []map[string]string{cluster, namespaced},
+			want:     []string{"base", "child", "grandchild", "team"},
+		},
+		{
+			name:     "self",
+			template: "self",
+			bases:    []map[string]string{cluster, namespaced},
+			want:     []string{"self"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dependentTemplateNames(tt.template, tt.bases...)
+			if diff := cmp.Diff(tt.want, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("dependentTemplateNames() diff: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	imageutil "github.com/takutakahashi/oci-image-operator/pkg/image"
+)
+
+// ImageFlowTemplateReconciler reconciles a ImageFlowTemplate object
+type ImageFlowTemplateReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=build.takutakahashi.dev,resources=imageflowtemplates/status,verbs=get;update;patch
+
+func (r *ImageFlowTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	imt := &buildv1beta1.ImageFlowTemplate{}
+	if err := r.Get(ctx, req.NamespacedName, imt); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	images := &buildv1beta1.ImageList{}
+	if err := r.List(ctx, images, client.InNamespace(imt.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	refs := []templateReference{}
+	for i := range images.Items {
+		if phases := referringPhases(&images.Items[i], imt.Name, false); len(phases) != 0 {
+			refs = append(refs, templateReference{image: &images.Items[i], phases: phases})
+		}
+	}
+	resolved, err := buildv1beta1.GetTemplate(ctx, r.Client, imt.Namespace, buildv1beta1.ImageFlowTemplateRef{Name: imt.Name, Kind: buildv1beta1.ImageFlowTemplateKindNamespaced})
+	status := templateStatus(imt.Generation, imt.Status, resolved, err, refs)
+	if equality.Semantic.DeepEqual(imt.Status, status) {
+		return ctrl.Result{}, nil
+	}
+	imt.Status = status
+	return ctrl.Result{}, r.Status().Update(ctx, imt, &client.UpdateOptions{})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Templates are reconciled again when the templates which they extend change.
+func (r *ImageFlowTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&buildv1beta1.ImageFlowTemplate{}).
+		Watches(&source.Kind{Type: &buildv1beta1.Image{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			return templateRequests(obj, false)
+		})).
+		Watches(&source.Kind{Type: &buildv1beta1.ImageFlowTemplate{}}, handler.EnqueueRequestsFromMapFunc(r.templatesForTemplate)).
+		Watches(&source.Kind{Type: &buildv1beta1.ClusterImageFlowTemplate{}}, handler.EnqueueRequestsFromMapFunc(r.templatesForClusterTemplate)).
+		Complete(r)
+}
+
+// templatesForTemplate maps an ImageFlowTemplate to the templates in its namespace which extend it.
+func (r *ImageFlowTemplateReconciler) templatesForTemplate(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	namespaced, cluster, err := templateBases(ctx, r.Client, obj.GetNamespace())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list templates", "template", obj.GetName())
+		return nil
+	}
+	bases := namespaced[obj.GetNamespace()]
+	return dependentTemplateRequests(dependentTemplateNames(obj.GetName(), cluster, bases), obj.GetNamespace(), bases)
+}
+
+// templatesForClusterTemplate maps a ClusterImageFlowTemplate to the namespaced templates which extend it in all namespaces.
+func (r *ImageFlowTemplateReconciler) templatesForClusterTemplate(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	namespaced, cluster, err := templateBases(ctx, r.Client, "")
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list templates", "template", obj.GetName())
+		return nil
+	}
+	ret := []reconcile.Request{}
+	for namespace, bases := range namespaced {
+		ret = append(ret, dependentTemplateRequests(dependentTemplateNames(obj.GetName(), cluster, bases), namespace, bases)...)
+	}
+	return ret
+}
+
+// ClusterImageFlowTemplateReconciler reconciles a ClusterImageFlowTemplate object
+type ClusterImageFlowTemplateReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=build.takutakahashi.dev,resources=clusterimageflowtemplates/status,verbs=get;update;patch
+
+func (r *ClusterImageFlowTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	cimt := &buildv1beta1.ClusterImageFlowTemplate{}
+	if err := r.Get(ctx, req.NamespacedName, cimt); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	images := &buildv1beta1.ImageList{}
+	if err := r.List(ctx, images); err != nil {
+		return ctrl.Result{}, err
+	}
+	refs := []templateReference{}
+	shadowed := map[string]bool{}
+	for i := range images.Items {
+		image := &images.Items[i]
+		phases := referringPhases(image, cimt.Name, true)
+		if len(phases) == 0 {
+			continue
+		}
+		// a namespaced template with the same name takes precedence
+		s, ok := shadowed[image.Namespace]
+		if !ok {
+			err := r.Get(ctx, types.NamespacedName{Name: cimt.Name, Namespace: image.Namespace}, &buildv1beta1.ImageFlowTemplate{})
+			if err != nil && !errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			s = err == nil
+			shadowed[image.Namespace] = s
+		}
+		if !s {
+			refs = append(refs, templateReference{image: image, phases: phases})
+		}
+	}
+	resolved, err := buildv1beta1.GetTemplate(ctx, r.Client, "", buildv1beta1.ImageFlowTemplateRef{Name: cimt.Name, Kind: buildv1beta1.ImageFlowTemplateKindCluster})
+	status := templateStatus(cimt.Generation, cimt.Status, resolved, err, refs)
+	if equality.Semantic.DeepEqual(cimt.Status, status) {
+		return ctrl.Result{}, nil
+	}
+	cimt.Status = status
+	return ctrl.Result{}, r.Status().Update(ctx, cimt, &client.UpdateOptions{})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Templates are reconciled again when the templates which they extend change.
+func (r *ClusterImageFlowTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&buildv1beta1.ClusterImageFlowTemplate{}).
+		Watches(&source.Kind{Type: &buildv1beta1.Image{}}, handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			return templateRequests(obj, true)
+		})).
+		Watches(&source.Kind{Type: &buildv1beta1.ClusterImageFlowTemplate{}}, handler.EnqueueRequestsFromMapFunc(r.templatesForClusterTemplate)).
+		Complete(r)
+}
+
+// templatesForClusterTemplate maps a ClusterImageFlowTemplate to the cluster templates which extend it.
+func (r *ClusterImageFlowTemplateReconciler) templatesForClusterTemplate(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	_, cluster, err := templateBases(ctx, r.Client, metav1.NamespaceNone)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list templates", "template", obj.GetName())
+		return nil
+	}
+	return dependentTemplateRequests(dependentTemplateNames(obj.GetName(), cluster), metav1.NamespaceNone, cluster)
+}
+
+// dependentTemplateRequests returns requests of the templates in namespace among names.
+// Only templates in bases are requested since templates without base extend nothing.
+func dependentTemplateRequests(names []string, namespace string, bases map[string]string) []reconcile.Request {
+	ret := []reconcile.Request{}
+	for _, name := range names {
+		if _, ok := bases[name]; ok {
+			ret = append(ret, reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}})
+		}
+	}
+	return ret
+}
+
+// templateReference is an Image which refers the template in phases.
+type templateReference struct {
+	image  *buildv1beta1.Image
+	phases []buildv1beta1.ImageFlowPhase
+}
+
+// referringPhases returns the phases in which the image refers the template.
+func referringPhases(image *buildv1beta1.Image, name string, cluster bool) []buildv1beta1.ImageFlowPhase {
+	ret := []buildv1beta1.ImageFlowPhase{}
+	for _, phase := range buildv1beta1.ImageFlowPhases {
+		ref := image.PhaseTemplateRef(phase)
+		if ref.Name != name {
+			continue
+		}
+		if cluster && ref.Kind != buildv1beta1.ImageFlowTemplateKindCluster {
+			continue
+		}
+		ret = append(ret, phase)
+	}
+	return ret
+}
+
+// templateRequests maps an Image to the templates which it refers.
+func templateRequests(obj client.Object, cluster bool) []reconcile.Request {
+	image, ok := obj.(*buildv1beta1.Image)
+	if !ok {
+		return nil
+	}
+	ret := []reconcile.Request{}
+	seen := map[types.NamespacedName]bool{}
+	for _, phase := range buildv1beta1.ImageFlowPhases {
+		ref := image.PhaseTemplateRef(phase)
+		if ref.Name == "" || (cluster && ref.Kind != buildv1beta1.ImageFlowTemplateKindCluster) {
+			continue
+		}
+		nn := types.NamespacedName{Name: ref.Name}
+		if !cluster {
+			nn.Namespace = image.Namespace
+		}
+		if !seen[nn] {
+			seen[nn] = true
+			ret = append(ret, reconcile.Request{NamespacedName: nn})
+		}
+	}
+	return ret
+}
+
+// templateStatus validates the resolved template and the RequiredEnv of the Images which refer it.
+func templateStatus(generation int64, current buildv1beta1.ImageFlowTemplateStatus, resolved *buildv1beta1.ImageFlowTemplate, resolveErr error, refs []templateReference) buildv1beta1.ImageFlowTemplateStatus {
+	status := *current.DeepCopy()
+	status.ObservedGeneration = generation
+	ready := metav1.Condition{
+		Type:               buildv1beta1.ImageFlowTemplateConditionTypeReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             imageutil.ReasonTemplateValid,
+		Message:            "template is valid",
+	}
+	if resolveErr != nil {
+		ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, "BaseTemplateNotReady", resolveErr.Error()
+		resolved = nil
+	} else if errs := resolved.ValidateResolvedSpec(); len(errs) != 0 {
+		ready.Status, ready.Reason, ready.Message = metav1.ConditionFalse, imageutil.ReasonTemplateInvalid, errs.ToAggregate().Error()
+	}
+	meta.SetStatusCondition(&status.Conditions, ready)
+
+	env := metav1.Condition{
+		Type:               buildv1beta1.ImageFlowTemplateConditionTypeRequiredEnvSatisfied,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Satisfied",
+		Message:            "all images have required env",
+	}
+	status.Images = nil
+	missing := []string{}
+	for _, ref := range refs {
+		name := types.NamespacedName{Name: ref.image.Name, Namespace: ref.image.Namespace}.String()
+		status.Images = append(status.Images, name)
+		if resolved == nil {
+			continue
+		}
+		if m := imageutil.MissingRequiredEnv(ref.image, phaseTemplate(resolved, ref.phases)); len(m) != 0 {
+			missing = append(missing, fmt.Sprintf("%s: %s", name, strings.Join(m, ", ")))
+		}
+	}
+	sort.Strings(status.Images)
+	sort.Strings(missing)
+	switch {
+	case resolved == nil:
+		env.Status, env.Reason, env.Message = metav1.ConditionUnknown, "TemplateNotReady", "template can not be resolved"
+	case len(missing) != 0:
+		env.Status, env.Reason, env.Message = metav1.ConditionFalse, imageutil.ReasonRequiredEnv, strings.Join(missing, "; ")
+	}
+	meta.SetStatusCondition(&status.Conditions, env)
+	return status
+}
+
+// phaseTemplate returns the template which has only the phases.
+func phaseTemplate(imt *buildv1beta1.ImageFlowTemplate, phases []buildv1beta1.ImageFlowPhase) *buildv1beta1.ImageFlowTemplate {
+	ret := &buildv1beta1.ImageFlowTemplate{ObjectMeta: imt.ObjectMeta}
+	for _, phase := range phases {
+		switch phase {
+		case buildv1beta1.ImageFlowPhaseDetect:
+			ret.Spec.Detect = imt.Spec.Detect
+		case buildv1beta1.ImageFlowPhaseCheck:
+			ret.Spec.Check = imt.Spec.Check
+		case buildv1beta1.ImageFlowPhaseUpload:
+			ret.Spec.Upload = imt.Spec.Upload
+		}
+	}
+	return ret
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTemplateStatus(t *testing.T) {
+	actor := &buildv1beta1.ContainerApplyConfiguration{Image: pointer.String("actor")}
+	resolved := &buildv1beta1.ImageFlowTemplate{
+		Spec: buildv1beta1.ImageFlowTemplateSpec{
+			Detect: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor, RequiredEnv: []string{"GITHUB_TOKEN"}},
+			Check:  buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor},
+			Upload: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor},
+		},
+	}
+	image := func(name string, env ...string) *buildv1beta1.Image {
+		i := &buildv1beta1.Image{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		for _, e := range env {
+			i.Spec.Env = append(i.Spec.Env, corev1.EnvVar{Name: e, Value: "value"})
+		}
+		return i
+	}
+	all := buildv1beta1.ImageFlowPhases
+	tests := []struct {
+		name       string
+		resolved   *buildv1beta1.ImageFlowTemplate
+		resolveErr error
+		refs       []templateReference
+		ready      metav1.ConditionStatus
+		env        metav1.ConditionStatus
+		images     []string
+	}{
+		{
+			name:     "satisfied",
+			resolved: resolved,
+			refs:     []templateReference{{image: image("b", "GITHUB_TOKEN"), phases: all}, {image: image("a", "GITHUB_TOKEN"), phases: all}},
+			ready:    metav1.ConditionTrue,
+			env:      metav1.ConditionTrue,
+			images:   []string{"default/a", "default/b"},
+		},
+		{
+			name:     "missing_env",
+			resolved: resolved,
+			refs:     []templateReference{{image: image("a"), phases: all}},
+			ready:    metav1.ConditionTrue,
+			env:      metav1.ConditionFalse,
+			images:   []string{"default/a"},
+		},
+		{
+			name:     "other_phase",
+			resolved: resolved,
+			refs:     []templateReference{{image: image("a"), phases: []buildv1beta1.ImageFlowPhase{buildv1beta1.ImageFlowPhaseCheck}}},
+			ready:    metav1.ConditionTrue,
+			env:      metav1.ConditionTrue,
+			images:   []string{"default/a"},
+		},
+		{
+			name:       "base_not_found",
+			resolveErr: errors.New("base not found"),
+			refs:       []templateReference{{image: image("a"), phases: all}},
+			ready:      metav1.ConditionFalse,
+			env:        metav1.ConditionUnknown,
+			images:     []string{"default/a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := templateStatus(2, buildv1beta1.ImageFlowTemplateStatus{}, tt.resolved, tt.resolveErr, tt.refs)
+			if got.ObservedGeneration != 2 {
+				t.Errorf("ObservedGeneration = %d", got.ObservedGeneration)
+			}
+			if c := meta.FindStatusCondition(got.Conditions, buildv1beta1.ImageFlowTemplateConditionTypeReady); c == nil || c.Status != tt.ready {
+				t.Errorf("Ready = %v, want %s", c, tt.ready)
+			}
+			if c := meta.FindStatusCondition(got.Conditions, buildv1beta1.ImageFlowTemplateConditionTypeRequiredEnvSatisfied); c == nil || c.Status != tt.env {
+				t.Errorf("RequiredEnvSatisfied = %v, want %s", c, tt.env)
+			}
+			if len(got.Images) != len(tt.images) {
+				t.Fatalf("Images = %v, want %v", got.Images, tt.images)
+			}
+			for i := range tt.images {
+				if got.Images[i] != tt.images[i] {
+					t.Errorf("Images = %v, want %v", got.Images, tt.images)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplatesForBaseTemplate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := buildv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	namespaced := func(name, namespace, base string) *buildv1beta1.ImageFlowTemplate {
+		return &buildv1beta1.ImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       buildv1beta1.ImageFlowTemplateSpec{BaseImage: base},
+		}
+	}
+	cluster := func(name, base string) *buildv1beta1.ClusterImageFlowTemplate {
+		return &buildv1beta1.ClusterImageFlowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       buildv1beta1.ImageFlowTemplateSpec{BaseImage: base},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		cluster("all", ""),
+		cluster("go", "all"),
+		namespaced("base", "default", ""),
+		namespaced("app", "default", "base"),
+		namespaced("app-debug", "default", "app"),
+		namespaced("other", "default", ""),
+		namespaced("tools", "team", "go"),
+		namespaced("app", "team", "base"),
+	).Build()
+	request := func(name, namespace string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	}
+	sortRequests := cmpopts.SortSlices(func(a, b reconcile.Request) bool { return a.String() < b.String() })
+
+	r := &ImageFlowTemplateReconciler{Client: c}
+	got := r.templatesForTemplate(namespaced("base", "default", ""))
+	if diff := cmp.Diff([]reconcile.Request{request("app", "default"), request("app-debug", "default")}, got, sortRequests); diff != "" {
+		t.Errorf("templatesForTemplate() diff: %s", diff)
+	}
+	got = r.templatesForClusterTemplate(cluster("all", ""))
+	if diff := cmp.Diff([]reconcile.Request{request("tools", "team")}, got, sortRequests); diff != "" {
+		t.Errorf("templatesForClusterTemplate() diff: %s", diff)
+	}
+	cr := &ClusterImageFlowTemplateReconciler{Client: c}
+	got = cr.templatesForClusterTemplate(cluster("all", ""))
+	if diff := cmp.Diff([]reconcile.Request{request("go", "")}, got, sortRequests); diff != "" {
+		t.Errorf("cluster templatesForClusterTemplate() diff: %s", diff)
+	}
+}
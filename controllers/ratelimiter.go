@@ -0,0 +1,70 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+)
+
+const (
+	// DefaultRateLimiterBaseDelay is the delay after the first failure of an Image.
+	DefaultRateLimiterBaseDelay = 5 * time.Millisecond
+	// DefaultRateLimiterMaxDelay caps the delay which doubles on each failure of an Image.
+	DefaultRateLimiterMaxDelay = 1000 * time.Second
+	// DefaultRateLimiterQPS is the overall rate of requeues of all Images.
+	DefaultRateLimiterQPS = 10
+	// DefaultRateLimiterBurst is the overall burst of requeues of all Images.
+	DefaultRateLimiterBurst = 100
+)
+
+// RateLimiterOptions configures the workqueue rate limiter which backs off Images failing with transient errors.
+// The defaults are the same as the default rate limiter of controller-runtime.
+type RateLimiterOptions struct {
+	// BaseDelay is the delay after the first failure of an Image. DefaultRateLimiterBaseDelay is used when it is zero.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay of an Image. DefaultRateLimiterMaxDelay is used when it is zero.
+	MaxDelay time.Duration
+	// QPS limits requeues of all Images. DefaultRateLimiterQPS is used when it is zero.
+	QPS float64
+	// Burst is the burst of requeues of all Images. DefaultRateLimiterBurst is used when it is zero.
+	Burst int
+}
+
+// NewRateLimiter returns a rate limiter which delays an Image exponentially on each failure,
+// and limits the overall rate with a token bucket.
+func NewRateLimiter(o RateLimiterOptions) ratelimiter.RateLimiter {
+	if o.BaseDelay == 0 {
+		o.BaseDelay = DefaultRateLimiterBaseDelay
+	}
+	if o.MaxDelay == 0 {
+		o.MaxDelay = DefaultRateLimiterMaxDelay
+	}
+	if o.QPS == 0 {
+		o.QPS = DefaultRateLimiterQPS
+	}
+	if o.Burst == 0 {
+		o.Burst = DefaultRateLimiterBurst
+	}
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(o.BaseDelay, o.MaxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(o.QPS), o.Burst)},
+	)
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RateLimiterOptions
+		want []time.Duration
+	}{
+		{
+			name: "default",
+			want: []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond},
+		},
+		{
+			name: "max_delay",
+			opts: RateLimiterOptions{BaseDelay: time.Second, MaxDelay: 3 * time.Second},
+			want: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(tt.opts)
+			for i, want := range tt.want {
+				if got := limiter.When("default/test"); got != want {
+					t.Errorf("delay of failure %d = %s, want %s", i+1, got, want)
+				}
+			}
+			if got := limiter.NumRequeues("default/test"); got != len(tt.want) {
+				t.Errorf("NumRequeues() = %d, want %d", got, len(tt.want))
+			}
+			limiter.Forget("default/test")
+			if got := limiter.When("default/test"); got != tt.want[0] {
+				t.Errorf("delay after Forget() = %s, want %s", got, tt.want[0])
+			}
+		})
+	}
+}
@@ -26,13 +26,16 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	buildv1 "github.com/takutakahashi/oci-image-operator/api/v1"
 	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
 	"github.com/takutakahashi/oci-image-operator/controllers"
+	imageutil "github.com/takutakahashi/oci-image-operator/pkg/image"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,6 +48,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(buildv1beta1.AddToScheme(scheme))
+	utilruntime.Must(buildv1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -52,11 +56,31 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var actorOpts imageutil.Options
+	var rateLimiterOpts controllers.RateLimiterOptions
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&actorOpts.Namespace, "actor-namespace", imageutil.DefaultActorNamespace,
+		"The namespace where actors run unless they run in the namespace of each Image.")
+	flag.BoolVar(&actorOpts.InImageNamespace, "actors-in-image-namespace", false,
+		"Run actors in the namespace of each Image with owner references. "+
+			"Actors which were created in --actor-namespace are moved on the next reconcile.")
+	flag.DurationVar(&actorOpts.DetectInterval, "detect-interval", imageutil.DefaultDetectInterval,
+		"The interval of polls of the detect actors.")
+	flag.IntVar(&actorOpts.DetectStaleIntervals, "detect-stale-intervals", imageutil.DefaultDetectStaleIntervals,
+		"The number of detect intervals without a successful poll after which DetectorHealthy becomes False, "+
+			"in addition to the interval at which polls without changes are recorded.")
+	flag.DurationVar(&rateLimiterOpts.BaseDelay, "rate-limiter-base-delay", controllers.DefaultRateLimiterBaseDelay,
+		"The delay before an Image is reconciled again after the first transient error. It doubles on each error.")
+	flag.DurationVar(&rateLimiterOpts.MaxDelay, "rate-limiter-max-delay", controllers.DefaultRateLimiterMaxDelay,
+		"The maximum delay before an Image is reconciled again after transient errors.")
+	flag.Float64Var(&rateLimiterOpts.QPS, "rate-limiter-qps", controllers.DefaultRateLimiterQPS,
+		"The overall rate of Images which are reconciled again after errors.")
+	flag.IntVar(&rateLimiterOpts.Burst, "rate-limiter-burst", controllers.DefaultRateLimiterBurst,
+		"The overall burst of Images which are reconciled again after errors.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -78,14 +102,54 @@ func main() {
 		os.Exit(1)
 	}
 
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create clientset")
+		os.Exit(1)
+	}
 	if err = (&controllers.ImageReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("image-controller"),
+		Client:      mgr.GetClient(),
+		Clientset:   clientset,
+		Scheme:      mgr.GetScheme(),
+		Recorder:    mgr.GetEventRecorderFor("image-controller"),
+		Actor:       actorOpts,
+		RateLimiter: controllers.NewRateLimiter(rateLimiterOpts),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Image")
 		os.Exit(1)
 	}
+	if err = (&controllers.ImageFlowTemplateReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageFlowTemplate")
+		os.Exit(1)
+	}
+	if err = (&controllers.ClusterImageFlowTemplateReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterImageFlowTemplate")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&buildv1beta1.Image{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Image")
+			os.Exit(1)
+		}
+		if err = (&buildv1beta1.ImageFlowTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ImageFlowTemplate")
+			os.Exit(1)
+		}
+		if err = (&buildv1beta1.ClusterImageFlowTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterImageFlowTemplate")
+			os.Exit(1)
+		}
+		if err = (&buildv1.Image{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Image")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,111 @@
+package image
+
+import (
+	"fmt"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+const (
+	// repositoryAuthPrefix is the prefix of the default auth env of detect.
+	repositoryAuthPrefix = "REPOSITORY_AUTH"
+	// registryAuthPrefix is the prefix of the default auth env of check and upload.
+	registryAuthPrefix = "REGISTRY_AUTH"
+)
+
+// defaultAuthEnv returns the env of the auth type with the prefix.
+func defaultAuthEnv(prefix string, t buildv1beta1.ImageAuthType) []buildv1beta1.ImageFlowTemplateAuthEnv {
+	switch t {
+	case buildv1beta1.ImageAuthTypeDockerConfigJSON:
+		return []buildv1beta1.ImageFlowTemplateAuthEnv{
+			{Name: prefix + "_DOCKERCONFIGJSON", Key: corev1.DockerConfigJsonKey},
+		}
+	case buildv1beta1.ImageAuthTypeToken:
+		return []buildv1beta1.ImageFlowTemplateAuthEnv{
+			{Name: prefix + "_TOKEN", Key: "token"},
+		}
+	}
+	return []buildv1beta1.ImageFlowTemplateAuthEnv{
+		{Name: prefix + "_USERNAME", Key: "username"},
+		{Name: prefix + "_PASSWORD", Key: "password"},
+	}
+}
+
+// authEnvMapping returns the auth env of the phase for the auth.
+// AuthEnv of the template is used when some of them match the auth type, otherwise the default of the type is used.
+func authEnvMapping(phase buildv1beta1.ImageFlowPhase, spec *buildv1beta1.ImageFlowTemplateSpecTemplate, auth buildv1beta1.ImageAuth) []buildv1beta1.ImageFlowTemplateAuthEnv {
+	t := auth.Type
+	if t == "" {
+		t = buildv1beta1.ImageAuthTypeBasic
+	}
+	ret := []buildv1beta1.ImageFlowTemplateAuthEnv{}
+	for _, env := range spec.AuthEnv {
+		if env.Type == "" || env.Type == t {
+			ret = append(ret, env)
+		}
+	}
+	if len(ret) > 0 {
+		return ret
+	}
+	if phase == buildv1beta1.ImageFlowPhaseDetect {
+		return defaultAuthEnv(repositoryAuthPrefix, t)
+	}
+	return defaultAuthEnv(registryAuthPrefix, t)
+}
+
+// authEnv returns env which refers keys of the auth secret.
+// Keys which are not in the secret are skipped not to block the pod. All keys are referred when the secret is unknown.
+func authEnv(auth buildv1beta1.ImageAuth, secret *corev1.Secret, mapping []buildv1beta1.ImageFlowTemplateAuthEnv) []*corev1apply.EnvVarApplyConfiguration {
+	ret := []*corev1apply.EnvVarApplyConfiguration{}
+	if auth.SecretName == "" {
+		return ret
+	}
+	for _, m := range mapping {
+		if secret != nil {
+			if _, ok := secret.Data[m.Key]; !ok {
+				continue
+			}
+		}
+		ret = append(ret, corev1apply.EnvVar().WithName(m.Name).WithValueFrom(
+			corev1apply.EnvVarSource().WithSecretKeyRef(corev1apply.SecretKeySelector().WithName(auth.SecretName).WithKey(m.Key))))
+	}
+	return ret
+}
+
+// repositoryAuthEnvVar returns the auth env of the repository for detect.
+func repositoryAuthEnvVar(image *buildv1beta1.Image, spec *buildv1beta1.ImageFlowTemplateSpecTemplate, secrets map[string]*corev1.Secret) []*corev1apply.EnvVarApplyConfiguration {
+	auth := image.Spec.Repository.Auth
+	return authEnv(auth, secrets[fmt.Sprintf("repository/%s", auth.SecretName)], authEnvMapping(buildv1beta1.ImageFlowPhaseDetect, spec, auth))
+}
+
+// targetAuthEnvVar returns the auth env of the target for check and upload.
+func targetAuthEnvVar(target buildv1beta1.ImageTarget, phase buildv1beta1.ImageFlowPhase, spec *buildv1beta1.ImageFlowTemplateSpecTemplate, secrets map[string]*corev1.Secret) []*corev1apply.EnvVarApplyConfiguration {
+	return authEnv(target.Auth, secrets[fmt.Sprintf("targets/%s", target.Auth.SecretName)], authEnvMapping(phase, spec, target.Auth))
+}
+
+// authEnvNames returns the names of auth env which every actor of the phase gets.
+func authEnvNames(image *buildv1beta1.Image, phase buildv1beta1.ImageFlowPhase, spec *buildv1beta1.ImageFlowTemplateSpecTemplate) []string {
+	auths := []buildv1beta1.ImageAuth{image.Spec.Repository.Auth}
+	if phase != buildv1beta1.ImageFlowPhaseDetect {
+		auths = []buildv1beta1.ImageAuth{}
+		for _, target := range image.Spec.Targets {
+			auths = append(auths, target.Auth)
+		}
+	}
+	count := map[string]int{}
+	ret := []string{}
+	for _, auth := range auths {
+		if auth.SecretName == "" {
+			return nil
+		}
+		for _, m := range authEnvMapping(phase, spec, auth) {
+			count[m.Name]++
+			if count[m.Name] == len(auths) {
+				ret = append(ret, m.Name)
+			}
+		}
+	}
+	return ret
+}
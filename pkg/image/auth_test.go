@@ -0,0 +1,166 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+func TestAuthEnv(t *testing.T) {
+	auth := buildv1beta1.ImageAuth{Type: buildv1beta1.ImageAuthTypeBasic, SecretName: "secret"}
+	tests := []struct {
+		name    string
+		auth    buildv1beta1.ImageAuth
+		secret  *corev1.Secret
+		mapping []buildv1beta1.ImageFlowTemplateAuthEnv
+		want    map[string]string
+	}{
+		{
+			name:    "no_auth",
+			mapping: defaultAuthEnv(registryAuthPrefix, buildv1beta1.ImageAuthTypeBasic),
+			want:    map[string]string{},
+		},
+		{
+			name:    "unknown_secret",
+			auth:    auth,
+			mapping: defaultAuthEnv(registryAuthPrefix, buildv1beta1.ImageAuthTypeBasic),
+			want:    map[string]string{"REGISTRY_AUTH_USERNAME": "username", "REGISTRY_AUTH_PASSWORD": "password"},
+		},
+		{
+			name:    "missing_key",
+			auth:    auth,
+			secret:  &corev1.Secret{Data: map[string][]byte{"password": []byte("token")}},
+			mapping: defaultAuthEnv(repositoryAuthPrefix, buildv1beta1.ImageAuthTypeBasic),
+			want:    map[string]string{"REPOSITORY_AUTH_PASSWORD": "password"},
+		},
+		{
+			name:    "custom",
+			auth:    auth,
+			secret:  &corev1.Secret{Data: map[string][]byte{"token": []byte("token")}},
+			mapping: []buildv1beta1.ImageFlowTemplateAuthEnv{{Name: "GITHUB_TOKEN", Key: "token"}},
+			want:    map[string]string{"GITHUB_TOKEN": "token"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			for _, env := range authEnv(tt.auth, tt.secret, tt.mapping) {
+				if *env.ValueFrom.SecretKeyRef.Name != tt.auth.SecretName {
+					t.Errorf("secret of %s = %s", *env.Name, *env.ValueFrom.SecretKeyRef.Name)
+				}
+				got[*env.Name] = *env.ValueFrom.SecretKeyRef.Key
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("authEnv() diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestAuthEnvMapping(t *testing.T) {
+	custom := []buildv1beta1.ImageFlowTemplateAuthEnv{
+		{Name: "GITHUB_TOKEN", Key: "password", Type: buildv1beta1.ImageAuthTypeBasic},
+		{Name: "DOCKER_CONFIG_JSON", Key: ".dockerconfigjson", Type: buildv1beta1.ImageAuthTypeDockerConfigJSON},
+	}
+	tests := []struct {
+		name    string
+		phase   buildv1beta1.ImageFlowPhase
+		authEnv []buildv1beta1.ImageFlowTemplateAuthEnv
+		auth    buildv1beta1.ImageAuthType
+		want    []string
+	}{
+		{
+			name:  "basic",
+			phase: buildv1beta1.ImageFlowPhaseDetect,
+			want:  []string{"REPOSITORY_AUTH_USERNAME", "REPOSITORY_AUTH_PASSWORD"},
+		},
+		{
+			name:  "dockerconfigjson",
+			phase: buildv1beta1.ImageFlowPhaseCheck,
+			auth:  buildv1beta1.ImageAuthTypeDockerConfigJSON,
+			want:  []string{"REGISTRY_AUTH_DOCKERCONFIGJSON"},
+		},
+		{
+			name:  "token",
+			phase: buildv1beta1.ImageFlowPhaseUpload,
+			auth:  buildv1beta1.ImageAuthTypeToken,
+			want:  []string{"REGISTRY_AUTH_TOKEN"},
+		},
+		{
+			name:    "custom",
+			phase:   buildv1beta1.ImageFlowPhaseCheck,
+			authEnv: custom,
+			auth:    buildv1beta1.ImageAuthTypeDockerConfigJSON,
+			want:    []string{"DOCKER_CONFIG_JSON"},
+		},
+		{
+			name:    "custom_other_type",
+			phase:   buildv1beta1.ImageFlowPhaseCheck,
+			authEnv: custom,
+			auth:    buildv1beta1.ImageAuthTypeToken,
+			want:    []string{"REGISTRY_AUTH_TOKEN"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, env := range authEnvMapping(tt.phase, &buildv1beta1.ImageFlowTemplateSpecTemplate{AuthEnv: tt.authEnv}, buildv1beta1.ImageAuth{Type: tt.auth, SecretName: "secret"}) {
+				got = append(got, env.Name)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("authEnvMapping() diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestActorAuthEnv(t *testing.T) {
+	actor := &buildv1beta1.ContainerApplyConfiguration{}
+	template := &buildv1beta1.ImageFlowTemplate{
+		Spec: buildv1beta1.ImageFlowTemplateSpec{
+			Detect: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor, AuthEnv: []buildv1beta1.ImageFlowTemplateAuthEnv{{Name: "GITHUB_TOKEN", Key: "password"}}},
+			Check:  buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor},
+			Upload: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor},
+		},
+	}
+	image := &buildv1beta1.Image{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: buildv1beta1.ImageSpec{
+			Repository: buildv1beta1.ImageRepository{Auth: buildv1beta1.ImageAuth{Type: buildv1beta1.ImageAuthTypeBasic, SecretName: "github"}},
+			Targets:    []buildv1beta1.ImageTarget{{Name: "ghcr.io/takutakahashi/test", Auth: buildv1beta1.ImageAuth{Type: buildv1beta1.ImageAuthTypeBasic, SecretName: "ghcr"}}},
+		},
+	}
+	secrets := map[string]*corev1.Secret{
+		"repository/github": {Data: map[string][]byte{"username": []byte("user"), "password": []byte("token")}},
+		"targets/ghcr":      {Data: map[string][]byte{"username": []byte("user"), "password": []byte("token")}},
+	}
+	secretRefs := func(env []corev1apply.EnvVarApplyConfiguration) map[string]string {
+		ret := map[string]string{}
+		for _, e := range env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				ret[*e.Name] = *e.ValueFrom.SecretKeyRef.Name + "/" + *e.ValueFrom.SecretKeyRef.Key
+			}
+		}
+		return ret
+	}
+	deploy, err := detectDeployment(image, template, secrets, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"GITHUB_TOKEN": "github/password"}, secretRefs(deploy.Spec.Template.Spec.Containers[0].Env)); diff != "" {
+		t.Errorf("detect env diff: %s", diff)
+	}
+	cond := buildv1beta1.ImageCondition{Type: buildv1beta1.ImageConditionTypeUploaded, Revision: "master", ResolvedRevision: "aaa"}
+	job, err := uploadJob(image, template, image.Spec.Targets[0], cond, secrets, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"REGISTRY_AUTH_USERNAME": "ghcr/username", "REGISTRY_AUTH_PASSWORD": "ghcr/password"}
+	if diff := cmp.Diff(want, secretRefs(job.Spec.Template.Spec.Containers[0].Env)); diff != "" {
+		t.Errorf("upload env diff: %s", diff)
+	}
+}
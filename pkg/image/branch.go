@@ -0,0 +1,39 @@
+package image
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+)
+
+// AllBranches is passed to detect actors in TARGET_BRANCHES when all branches are needed to resolve branch patterns.
+const AllBranches = "*"
+
+// IsBranchPattern returns true when the policy matches branches by glob or regex.
+func IsBranchPattern(policy buildv1beta1.ImageTagPolicy) bool {
+	switch policy.Policy {
+	case buildv1beta1.ImageTagPolicyTypeBranchHash, buildv1beta1.ImageTagPolicyTypeBranchName:
+		return policy.Match == buildv1beta1.ImageTagPolicyMatchGlob || policy.Match == buildv1beta1.ImageTagPolicyMatchRegex
+	default:
+		return false
+	}
+}
+
+// MatchBranch returns true when the branch matches Revision of the policy.
+// glob is matched by path.Match, so * does not match "/". regex must match the whole branch name.
+func MatchBranch(policy buildv1beta1.ImageTagPolicy, branch string) (bool, error) {
+	switch policy.Match {
+	case buildv1beta1.ImageTagPolicyMatchGlob:
+		return path.Match(policy.Revision, branch)
+	case buildv1beta1.ImageTagPolicyMatchRegex:
+		re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", policy.Revision))
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(branch), nil
+	default:
+		return policy.Revision == branch, nil
+	}
+}
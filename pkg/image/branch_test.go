@@ -0,0 +1,72 @@
+package image
+
+import (
+	"testing"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+)
+
+func TestMatchBranch(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  buildv1beta1.ImageTagPolicy
+		branch  string
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "exact",
+			policy: buildv1beta1.ImageTagPolicy{Policy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "main"},
+			branch: "main",
+			want:   true,
+		},
+		{
+			name:   "exact_not_match",
+			policy: buildv1beta1.ImageTagPolicy{Policy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/*"},
+			branch: "release/2022-05",
+			want:   false,
+		},
+		{
+			name:   "glob",
+			policy: buildv1beta1.ImageTagPolicy{Policy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/*", Match: buildv1beta1.ImageTagPolicyMatchGlob},
+			branch: "release/2022-05",
+			want:   true,
+		},
+		{
+			name:   "glob_nested",
+			policy: buildv1beta1.ImageTagPolicy{Policy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release/*", Match: buildv1beta1.ImageTagPolicyMatchGlob},
+			branch: "release/2022/05",
+			want:   false,
+		},
+		{
+			name:   "regex",
+			policy: buildv1beta1.ImageTagPolicy{Policy: buildv1beta1.ImageTagPolicyTypeBranchHash, Revision: `release/\d+`, Match: buildv1beta1.ImageTagPolicyMatchRegex},
+			branch: "release/12",
+			want:   true,
+		},
+		{
+			name:   "regex_whole_name",
+			policy: buildv1beta1.ImageTagPolicy{Policy: buildv1beta1.ImageTagPolicyTypeBranchHash, Revision: `release/\d+`, Match: buildv1beta1.ImageTagPolicyMatchRegex},
+			branch: "hotfix/release/12",
+			want:   false,
+		},
+		{
+			name:    "invalid_regex",
+			policy:  buildv1beta1.ImageTagPolicy{Policy: buildv1beta1.ImageTagPolicyTypeBranchHash, Revision: `release/(`, Match: buildv1beta1.ImageTagPolicyMatchRegex},
+			branch:  "release/12",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchBranch(tt.policy, tt.branch)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MatchBranch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("MatchBranch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
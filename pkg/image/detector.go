@@ -0,0 +1,119 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ReasonDetectorRunning     = "Running"
+	ReasonDetectorStale       = "Stale"
+	ReasonDetectorUnavailable = "Unavailable"
+)
+
+// UpdateDetectorCondition sets DetectorHealthy condition from the detect deployment and the last successful poll.
+// The detector is stale when it has been available but no poll is recorded within opts.DetectStaleAfter.
+// Pods are read with cs only when the deployment has no available pod, and they are not read when cs is nil.
+// The condition is not set until the deployment is created.
+func UpdateDetectorCondition(ctx context.Context, c client.Client, cs kubernetes.Interface, image *buildv1beta1.Image, opts Options) (*buildv1beta1.Image, error) {
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, client.ObjectKey{Name: fmt.Sprintf("%s-detect", image.Name), Namespace: opts.actorNamespace(image)}, deploy); apierrors.IsNotFound(err) {
+		return image, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get detect deployment")
+	}
+	image.Status.Conditions = SetStatusCondition(image.Status.Conditions, detectorCondition(ctx, cs, image, deploy, opts, time.Now()))
+	return image, nil
+}
+
+func detectorCondition(ctx context.Context, cs kubernetes.Interface, image *buildv1beta1.Image, deploy *appsv1.Deployment, opts Options, now time.Time) buildv1beta1.ImageCondition {
+	cond := buildv1beta1.ImageCondition{
+		Type:   buildv1beta1.ImageConditionTypeDetectorHealthy,
+		Status: buildv1beta1.ImageConditionStatusFalse,
+	}
+	available := deploymentCondition(deploy, appsv1.DeploymentAvailable)
+	if deploy.Status.AvailableReplicas == 0 {
+		cond.Reason, cond.Message = ReasonDetectorUnavailable, "detector has no available pod"
+		if available != nil && available.Message != "" {
+			cond.Message = available.Message
+		}
+		if reason, message := detectorPodProblem(ctx, cs, image, deploy); reason != "" {
+			cond.Reason, cond.Message = reason, message
+		}
+		return cond
+	}
+	// a restarted detector is given the same time as the first poll
+	last := image.Status.LastDetectTime
+	if available != nil && available.Status == corev1.ConditionTrue && (last == nil || last.Before(&available.LastTransitionTime)) {
+		last = &available.LastTransitionTime
+	}
+	if last != nil && now.Sub(last.Time) > opts.DetectStaleAfter() {
+		cond.Reason = ReasonDetectorStale
+		cond.Message = fmt.Sprintf("no successful poll is recorded since %s", last.UTC().Format(time.RFC3339))
+		return cond
+	}
+	cond.Status, cond.Reason = buildv1beta1.ImageConditionStatusTrue, ReasonDetectorRunning
+	return cond
+}
+
+func deploymentCondition(deploy *appsv1.Deployment, t appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i, cond := range deploy.Status.Conditions {
+		if cond.Type == t {
+			return &deploy.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// detectorPodProblem returns why the latest pod of the detector is not available.
+// Reasons of waiting containers, such as CrashLoopBackOff, are returned with the last termination and the end of its logs.
+func detectorPodProblem(ctx context.Context, cs kubernetes.Interface, image *buildv1beta1.Image, deploy *appsv1.Deployment) (string, string) {
+	if cs == nil || deploy.Spec.Selector == nil {
+		return "", ""
+	}
+	set := labels.Set{ImageNamespaceLabel: image.Namespace}
+	for k, v := range deploy.Spec.Selector.MatchLabels {
+		set[k] = v
+	}
+	pods, err := latestPods(ctx, cs, deploy.Namespace, set)
+	if err != nil {
+		logrus.Warnf("failed to get pods of detector %s: %v", deploy.Name, err)
+		return "", ""
+	}
+	if len(pods) == 0 {
+		return "", ""
+	}
+	pod := &pods[0]
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		waiting := status.State.Waiting
+		if waiting == nil || waiting.Reason == "" {
+			continue
+		}
+		messages := []string{}
+		if waiting.Message != "" {
+			messages = append(messages, waiting.Message)
+		}
+		if detail := terminationDetail(ctx, cs, pod, status); detail != "" {
+			messages = append(messages, detail)
+		}
+		return waiting.Reason, strings.Join(messages, "; ")
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			return cond.Reason, cond.Message
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,138 @@
+package image
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDetectorCondition(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	recent := metav1.NewTime(now.Add(-time.Minute))
+	old := metav1.NewTime(now.Add(-time.Hour))
+	crashing := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-detect-pod",
+			Namespace: DefaultActorNamespace,
+			Labels:    map[string]string{ImageLabel: "test", ImageNamespaceLabel: "default"},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:                 "main",
+					State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off restarting failed container"}},
+					LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		cs         kubernetes.Interface
+		available  int32
+		transition metav1.Time
+		lastDetect *metav1.Time
+		status     buildv1beta1.ImageConditionStatus
+		reason     string
+		message    []string
+	}{
+		{
+			name:      "crashloop",
+			cs:        kubefake.NewSimpleClientset(crashing),
+			available: 0,
+			status:    buildv1beta1.ImageConditionStatusFalse,
+			reason:    "CrashLoopBackOff",
+			message:   []string{"back-off restarting failed container", "exit code 1", "fake logs"},
+		},
+		{
+			name:      "unavailable_without_clientset",
+			available: 0,
+			status:    buildv1beta1.ImageConditionStatusFalse,
+			reason:    ReasonDetectorUnavailable,
+			message:   []string{"Deployment does not have minimum availability."},
+		},
+		{
+			name:       "running",
+			available:  1,
+			transition: old,
+			lastDetect: &recent,
+			status:     buildv1beta1.ImageConditionStatusTrue,
+			reason:     ReasonDetectorRunning,
+		},
+		{
+			name:       "stale",
+			available:  1,
+			transition: old,
+			lastDetect: &old,
+			status:     buildv1beta1.ImageConditionStatusFalse,
+			reason:     ReasonDetectorStale,
+			message:    []string{"2022-05-01T11:00:00Z"},
+		},
+		{
+			name:       "restarted",
+			available:  1,
+			transition: recent,
+			lastDetect: &old,
+			status:     buildv1beta1.ImageConditionStatusTrue,
+			reason:     ReasonDetectorRunning,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := newOptionsTestImage()
+			image.Status.LastDetectTime = tt.lastDetect
+			available := corev1.ConditionTrue
+			if tt.available == 0 {
+				available = corev1.ConditionFalse
+			}
+			deploy := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-detect", Namespace: DefaultActorNamespace},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{ImageLabel: "test"}},
+				},
+				Status: appsv1.DeploymentStatus{
+					AvailableReplicas: tt.available,
+					Conditions: []appsv1.DeploymentCondition{
+						{Type: appsv1.DeploymentAvailable, Status: available, LastTransitionTime: tt.transition, Message: "Deployment does not have minimum availability."},
+					},
+				},
+			}
+			got := detectorCondition(context.Background(), tt.cs, image, deploy, Options{}, now)
+			if got.Status != tt.status || got.Reason != tt.reason {
+				t.Errorf("condition = %s/%s, want %s/%s", got.Status, got.Reason, tt.status, tt.reason)
+			}
+			for _, m := range tt.message {
+				if !strings.Contains(got.Message, m) {
+					t.Errorf("message %q doesn't contain %q", got.Message, m)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateDetectorCondition_WithoutDeployment(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	image := newOptionsTestImage()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	got, err := UpdateDetectorCondition(context.Background(), c, nil, image, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := GetStatusCondition(got.Status.Conditions, buildv1beta1.ImageConditionTypeDetectorHealthy); ok {
+		t.Errorf("condition is set without deployment")
+	}
+}
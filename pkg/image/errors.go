@@ -0,0 +1,68 @@
+package image
+
+import (
+	"errors"
+	"time"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	ReasonReconciled      = "Succeeded"
+	ReasonSecretNotFound  = "SecretNotFound"
+	ReasonWorkloadInvalid = "WorkloadInvalid"
+	ReasonJobRunning      = "JobRunning"
+
+	// jobWaitInterval is the interval to check the previous job again.
+	// Jobs are watched, so it only covers events which are missed.
+	jobWaitInterval = time.Minute
+)
+
+// PermanentError is returned when the Image can't be reconciled until the Image or the resources which it refers change.
+// They are watched, so the Image is not reconciled again by the error.
+type PermanentError struct {
+	Reason  string
+	Message string
+}
+
+func (e *PermanentError) Error() string {
+	return e.Message
+}
+
+// WaitingError is returned when the Image waits for actor workloads. The Image is reconciled again after After.
+type WaitingError struct {
+	Reason  string
+	Message string
+	After   time.Duration
+}
+
+func (e *WaitingError) Error() string {
+	return e.Message
+}
+
+// AsPermanentError returns the PermanentError of err.
+// Requests which the API server rejects as invalid are permanent since the same workloads are rejected again.
+func AsPermanentError(err error) (*PermanentError, bool) {
+	var perr *PermanentError
+	if errors.As(err, &perr) {
+		return perr, true
+	}
+	if apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) {
+		return &PermanentError{Reason: ReasonWorkloadInvalid, Message: err.Error()}, true
+	}
+	return nil, false
+}
+
+// UpdateReconciledCondition sets Reconciled condition to False with the reason of the permanent error, or True when it is nil.
+func UpdateReconciledCondition(conditions []buildv1beta1.ImageCondition, err *PermanentError) []buildv1beta1.ImageCondition {
+	cond := buildv1beta1.ImageCondition{
+		Type:   buildv1beta1.ImageConditionTypeReconciled,
+		Status: buildv1beta1.ImageConditionStatusTrue,
+		Reason: ReasonReconciled,
+	}
+	if err != nil {
+		cond.Status, cond.Reason, cond.Message = buildv1beta1.ImageConditionStatusFalse, err.Reason, err.Message
+	}
+	return SetStatusCondition(conditions, cond)
+}
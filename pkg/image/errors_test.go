@@ -0,0 +1,104 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	batchv1apply "k8s.io/client-go/applyconfigurations/batch/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAsPermanentError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		want   bool
+		reason string
+	}{
+		{
+			name:   "permanent",
+			err:    pkgerrors.Wrap(&PermanentError{Reason: ReasonSecretNotFound, Message: "secret not found"}, "failed to gather resources"),
+			want:   true,
+			reason: ReasonSecretNotFound,
+		},
+		{
+			name:   "invalid",
+			err:    pkgerrors.Wrap(apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, "test-upload", nil), "failed to apply job"),
+			want:   true,
+			reason: ReasonWorkloadInvalid,
+		},
+		{
+			name: "not_found",
+			err:  apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "test"),
+		},
+		{
+			name: "waiting",
+			err:  &WaitingError{Reason: ReasonJobRunning, Message: "previous job is still running"},
+		},
+		{
+			name: "other",
+			err:  errors.New("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := AsPermanentError(tt.err)
+			if ok != tt.want {
+				t.Fatalf("AsPermanentError() = %v, want %v", ok, tt.want)
+			}
+			if ok && got.Reason != tt.reason {
+				t.Errorf("reason = %s, want %s", got.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestUpdateReconciledCondition(t *testing.T) {
+	conditions := []buildv1beta1.ImageCondition{
+		{Type: buildv1beta1.ImageConditionTypeChecked, Status: buildv1beta1.ImageConditionStatusTrue, Revision: "master"},
+	}
+	conditions = UpdateReconciledCondition(conditions, &PermanentError{Reason: ReasonSecretNotFound, Message: "secret not found"})
+	cond, ok := GetStatusCondition(conditions, buildv1beta1.ImageConditionTypeReconciled)
+	if !ok || cond.Status != buildv1beta1.ImageConditionStatusFalse || cond.Reason != ReasonSecretNotFound {
+		t.Fatalf("condition = %v", cond)
+	}
+	if Phase(conditions) != buildv1beta1.ImagePhaseFailed {
+		t.Errorf("phase should be failed when the image can't be reconciled")
+	}
+	conditions = UpdateReconciledCondition(conditions, nil)
+	if cond, _ := GetStatusCondition(conditions, buildv1beta1.ImageConditionTypeReconciled); cond.Status != buildv1beta1.ImageConditionStatusTrue || cond.Reason != ReasonReconciled || cond.Message != "" {
+		t.Errorf("condition = %v", cond)
+	}
+	if len(conditions) != 2 {
+		t.Errorf("conditions = %v", conditions)
+	}
+}
+
+func TestApplyJob_Running(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	running := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-upload", Namespace: DefaultActorNamespace},
+		Status:     batchv1.JobStatus{Active: 1},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(running).Build()
+	err := applyJob(context.Background(), c, batchv1apply.Job("test-upload", DefaultActorNamespace))
+	var werr *WaitingError
+	if !errors.As(err, &werr) || werr.Reason != ReasonJobRunning || werr.After != jobWaitInterval {
+		t.Errorf("applyJob() = %v, want waiting error", err)
+	}
+	if _, ok := AsPermanentError(err); ok {
+		t.Errorf("waiting error is permanent")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/google/go-cmp/cmp"
@@ -27,6 +29,7 @@ import (
 	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
 	metav1apply "k8s.io/client-go/applyconfigurations/meta/v1"
 	"k8s.io/utils/pointer"
+	"k8s.io/utils/strings/slices"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -35,32 +38,54 @@ const (
 	ttl          = 86400
 )
 
-func Ensure(ctx context.Context, c client.Client, image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, secrets map[string]*corev1.Secret) (*buildv1beta1.Image, error) {
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// Ensure creates the actor workloads of the image in the namespace chosen by opts.
+func Ensure(ctx context.Context, c client.Client, image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, secrets map[string]*corev1.Secret, opts Options) (*buildv1beta1.Image, error) {
+	if err := migrateWorkloads(ctx, c, image, opts); err != nil {
+		return nil, errors.Wrap(err, "failed to migrate workloads")
+	}
 	for _, cond := range image.Status.Conditions {
-		if err := cancelJob(ctx, c, image, cond); err != nil {
+		if err := cancelJob(ctx, c, image, cond, opts); err != nil {
 			return nil, err
 		}
 	}
+	if err := EnsureServiceAccount(ctx, c, image, opts); err != nil {
+		return nil, err
+	}
 	// TODO: refine status only uploaded
-	if after, err := EnsureDetect(ctx, c, image, template, secrets); err != nil || Diff(image, after) != "" {
+	if after, err := EnsureDetect(ctx, c, image, template, secrets, opts); err != nil || Diff(image, after) != "" {
 		return after, err
 	}
-	if after, err := EnsureCheck(ctx, c, image, template, secrets); err != nil || Diff(image, after) != "" {
+	// uploads of the other revisions don't wait for running checks
+	after, wait := EnsureCheck(ctx, c, image, template, secrets, opts)
+	if (wait != nil && !isWaiting(wait)) || Diff(image, after) != "" {
+		return after, wait
+	}
+	if after, err := EnsureUpload(ctx, c, image, template, secrets, opts); err != nil {
 		return after, err
 	}
-	return EnsureUpload(ctx, c, image, template, secrets)
+	return image, wait
+}
+
+func isWaiting(err error) bool {
+	var werr *WaitingError
+	return errors.As(err, &werr)
 }
 
 func Diff(before, after *buildv1beta1.Image) string {
-	opts := []cmp.Option{cmpopts.IgnoreFields(buildv1beta1.ImageCondition{}, "LastTransitionTime")}
-	return cmp.Diff(before.Status.Conditions, after.Status.Conditions, opts...)
+	opts := []cmp.Option{
+		cmpopts.IgnoreFields(buildv1beta1.ImageCondition{}, "LastTransitionTime"),
+		cmpopts.IgnoreFields(buildv1beta1.ImageLatestStatus{}, "LastTransitionTime"),
+	}
+	return cmp.Diff(before.Status, after.Status, opts...)
 }
 
-func EnsureDetect(ctx context.Context, c client.Client, image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, secrets map[string]*corev1.Secret) (*buildv1beta1.Image, error) {
+func EnsureDetect(ctx context.Context, c client.Client, image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, secrets map[string]*corev1.Secret, opts Options) (*buildv1beta1.Image, error) {
 	if image.DeletionTimestamp != nil {
 		if err := c.Delete(ctx, &appsv1.Deployment{
 			ObjectMeta: v1.ObjectMeta{
-				Name: fmt.Sprintf("%s-detect", image.Name), Namespace: "oci-image-operator-system",
+				Name: fmt.Sprintf("%s-detect", image.Name), Namespace: opts.actorNamespace(image),
 			},
 		}); client.IgnoreNotFound(err) != nil {
 			return image, err
@@ -69,7 +94,7 @@ func EnsureDetect(ctx context.Context, c client.Client, image *buildv1beta1.Imag
 			return image, c.Update(ctx, image, &client.UpdateOptions{})
 		}
 	}
-	deploy, err := detectDeployment(image, template)
+	deploy, err := detectDeployment(image, template, secrets, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +104,7 @@ func EnsureDetect(ctx context.Context, c client.Client, image *buildv1beta1.Imag
 	return image, nil
 }
 
-func EnsureCheck(ctx context.Context, c client.Client, image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, secrets map[string]*corev1.Secret) (*buildv1beta1.Image, error) {
+func EnsureCheck(ctx context.Context, c client.Client, image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, secrets map[string]*corev1.Secret, opts Options) (*buildv1beta1.Image, error) {
 	/**
 		1. check result of detect from status.
 		2. if changes of detect was not found, return the same image.
@@ -90,39 +115,51 @@ func EnsureCheck(ctx context.Context, c client.Client, image *buildv1beta1.Image
 	if len(conds) == 0 {
 		return image, nil
 	}
-	// FIXME: multiple targets
-	if len(image.Spec.Targets) != 1 {
-		return nil, fmt.Errorf("multiple targets is not supported now")
-	}
+	var wait error
 	for _, checkedCondition := range conds {
-		logrus.Info("checking image")
-		job, err := checkJob(image, template, checkedCondition)
+		target, ok := GetTarget(image.Spec.Targets, checkedCondition.Target)
+		if !ok {
+			logrus.Warnf("target %s is not found in spec, skipped", checkedCondition.Target)
+			continue
+		}
+		logrus.Infof("checking image for %s", target.Name)
+		job, err := checkJob(image, template, target, checkedCondition, secrets, opts)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to build job")
 		}
-		if err := applyJob(ctx, c, job); err != nil {
+		if err := applyJob(ctx, c, job); isWaiting(err) {
+			wait = err
+		} else if err != nil {
 			return nil, errors.Wrap(err, "failed to apply job")
 		}
 	}
-	return image, nil
+	return image, wait
 }
 
-func EnsureUpload(ctx context.Context, c client.Client, image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, secrets map[string]*corev1.Secret) (*buildv1beta1.Image, error) {
+func EnsureUpload(ctx context.Context, c client.Client, image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, secrets map[string]*corev1.Secret, opts Options) (*buildv1beta1.Image, error) {
 	conds := GetConditionByStatus(image.Status.Conditions, buildv1beta1.ImageConditionTypeUploaded, buildv1beta1.ImageConditionStatusFalse)
 	if conds == nil {
 		return image, nil
 	}
+	var wait error
 	for _, uploadedCondition := range conds {
-		logrus.Info("uploading image")
-		job, err := uploadJob(image, template, uploadedCondition)
+		target, ok := GetTarget(image.Spec.Targets, uploadedCondition.Target)
+		if !ok {
+			logrus.Warnf("target %s is not found in spec, skipped", uploadedCondition.Target)
+			continue
+		}
+		logrus.Infof("uploading image for %s", target.Name)
+		job, err := uploadJob(image, template, target, uploadedCondition, secrets, opts)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to build job")
 		}
-		if err := applyJob(ctx, c, job); err != nil {
+		if err := applyJob(ctx, c, job); isWaiting(err) {
+			wait = err
+		} else if err != nil {
 			return nil, errors.Wrap(err, "failed to apply job")
 		}
 	}
-	return image, nil
+	return image, wait
 }
 
 //func ReapStaleConditions(conds []buildv1beta1.ImageCondition) []buildv1beta1.ImageCondition {
@@ -133,32 +170,50 @@ func setLabel(name string, b map[string]string) map[string]string {
 	if b == nil {
 		b = map[string]string{}
 	}
-	b["build.takutakahashi.dev/image"] = name
+	b[ImageLabel] = name
 	return b
 }
 
-func detectDeployment(image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate) (*appsv1apply.DeploymentApplyConfiguration, error) {
+func detectDeployment(image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, secrets map[string]*corev1.Secret, opts Options) (*appsv1apply.DeploymentApplyConfiguration, error) {
 	tags, branches := []string{}, []string{}
 	for _, policy := range image.Spec.Repository.TagPolicies {
 		switch policy.Policy {
-		case buildv1beta1.ImageTagPolicyTypeBranchHash:
-			branches = append(branches, policy.Revision)
-		case buildv1beta1.ImageTagPolicyTypeTagHash:
-			tags = append(tags, policy.Revision)
+		case buildv1beta1.ImageTagPolicyTypeBranchHash, buildv1beta1.ImageTagPolicyTypeBranchName:
+			branch := policy.Revision
+			if IsBranchPattern(policy) {
+				branch = AllBranches
+			}
+			if !slices.Contains(branches, branch) {
+				branches = append(branches, branch)
+			}
+		case buildv1beta1.ImageTagPolicyTypeTagHash, buildv1beta1.ImageTagPolicyTypeTagName:
+			if !slices.Contains(tags, policy.Revision) {
+				tags = append(tags, policy.Revision)
+			}
+		case buildv1beta1.ImageTagPolicyTypeSemver:
+			if !slices.Contains(tags, AllTags) {
+				tags = append(tags, AllTags)
+			}
 		}
 	}
 	targetEnv := []*corev1apply.EnvVarApplyConfiguration{
 		corev1apply.EnvVar().WithName("TARGET_BRANCHES").WithValue(strings.Join(branches, ",")),
 		corev1apply.EnvVar().WithName("TARGET_TAGS").WithValue(strings.Join(tags, ",")),
 	}
-	podTemplate := corev1apply.PodTemplateSpec().WithSpec(corev1apply.PodSpec().
-		WithServiceAccountName("oci-image-operator-controller-manager").
-		WithVolumes(corev1apply.Volume().WithName("tmpdir").WithEmptyDir(corev1apply.EmptyDirVolumeSource())).
-		WithContainers(
-			actorContainer(image.Name, image.Namespace, &template.Spec.Detect, "detect").WithEnv(targetEnv...).WithEnv(toEnvVarConfiguration(image.Spec.Env)...),
-		))
-	deploy := appsv1apply.Deployment(fmt.Sprintf("%s-detect", image.Name), "oci-image-operator-system").
+	podSpec, err := actorPodSpec(&template.Spec.Detect, serviceAccountName(image, opts),
+		actorContainer(image.Name, image.Namespace, &template.Spec.Detect, "detect").
+			WithEnv(targetEnv...).
+			WithEnv(repositoryAuthEnvVar(image, &template.Spec.Detect, secrets)...).
+			WithEnv(toEnvVarConfiguration(image.Spec.Env)...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	podTemplate := corev1apply.PodTemplateSpec().WithSpec(podSpec)
+	deploy := appsv1apply.Deployment(fmt.Sprintf("%s-detect", image.Name), opts.actorNamespace(image)).
 		WithLabels(image.Labels).
+		WithLabels(actorLabels(image)).
+		WithOwnerReferences(opts.ownerReferences(image)...).
 		WithAnnotations(image.Annotations).
 		WithSpec(appsv1apply.DeploymentSpec().
 			WithReplicas(1).
@@ -166,39 +221,37 @@ func detectDeployment(image *buildv1beta1.Image, template *buildv1beta1.ImageFlo
 				metav1apply.LabelSelector().WithMatchLabels(
 					setLabel(image.Name, image.Labels))).
 			WithTemplate(podTemplate))
-	deploy.Spec.Template.ObjectMetaApplyConfiguration = metav1apply.ObjectMeta().WithLabels(setLabel(image.Name, image.Labels))
+	// the selector is immutable, so the namespace is only in the labels of pods
+	deploy.Spec.Template.ObjectMetaApplyConfiguration = metav1apply.ObjectMeta().WithLabels(setLabel(image.Name, image.Labels)).WithLabels(actorLabels(image))
 	return deploy, nil
 }
 
-func checkJob(image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, checkedCondition buildv1beta1.ImageCondition) (*batchv1apply.JobApplyConfiguration, error) {
+func checkJob(image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, target buildv1beta1.ImageTarget, checkedCondition buildv1beta1.ImageCondition, secrets map[string]*corev1.Secret, opts Options) (*batchv1apply.JobApplyConfiguration, error) {
 	revEnv := corev1apply.EnvVar().WithName("RESOLVED_REVISION").WithValue(checkedCondition.ResolvedRevision)
 	registryEnv := []*corev1apply.EnvVarApplyConfiguration{
-		corev1apply.EnvVar().WithName("REGISTRY_IMAGE_NAME").WithValue(image.Spec.Targets[0].Name),
+		corev1apply.EnvVar().WithName("IMAGE_TARGET").WithValue(target.Name),
+		corev1apply.EnvVar().WithName("REGISTRY_IMAGE_NAME").WithValue(target.Name),
 	}
-	if image.Spec.Targets[0].Auth.SecretName != "" {
-		registryEnv = append(registryEnv,
-			corev1apply.EnvVar().WithName("REGISTRY_AUTH_USERNAME").WithValueFrom(corev1apply.EnvVarSource().WithSecretKeyRef(corev1apply.SecretKeySelector().WithName(image.Spec.Targets[0].Auth.SecretName).WithKey("username"))),
-			corev1apply.EnvVar().WithName("REGISTRY_AUTH_PASSWORD").WithValueFrom(corev1apply.EnvVarSource().WithSecretKeyRef(corev1apply.SecretKeySelector().WithName(image.Spec.Targets[0].Auth.SecretName).WithKey("password"))),
-		)
+	registryEnv = append(registryEnv, targetAuthEnvVar(target, buildv1beta1.ImageFlowPhaseCheck, &template.Spec.Check, secrets)...)
+	podSpec, err := actorPodSpec(&template.Spec.Check, serviceAccountName(image, opts),
+		actorContainer(image.Name, image.Namespace, &template.Spec.Check, "check").WithEnv(revEnv).WithEnv(registryEnv...).WithEnv(toEnvVarConfiguration(image.Spec.Env)...),
+	)
+	if err != nil {
+		return nil, err
 	}
-	podTemplate := corev1apply.PodTemplateSpec().WithSpec(corev1apply.PodSpec().
-		WithRestartPolicy(corev1.RestartPolicyOnFailure).
-		WithServiceAccountName("oci-image-operator-controller-manager").
-		WithVolumes(corev1apply.Volume().WithName("tmpdir").WithEmptyDir(corev1apply.EmptyDirVolumeSource())).
-		WithContainers(
-			actorContainer(image.Name, image.Namespace, &template.Spec.Check, "check").WithEnv(revEnv).WithEnv(registryEnv...).WithEnv(toEnvVarConfiguration(image.Spec.Env)...),
-		))
-	// add sha256 from revision and tag policy
+	podTemplate := corev1apply.PodTemplateSpec().WithSpec(podSpec.WithRestartPolicy(corev1.RestartPolicyOnFailure))
+	// add sha256 from revision, tag policy and target
+	checkedCondition.Target = target.Name
 	name := genName(image.Name, checkedCondition)
-	job := batchv1apply.Job(name, "oci-image-operator-system").
+	job := batchv1apply.Job(name, opts.actorNamespace(image)).
 		WithLabels(image.Labels).
-		// TODO: add owner reference
-		WithOwnerReferences().
+		WithLabels(actorLabels(image)).
+		WithOwnerReferences(opts.ownerReferences(image)...).
 		WithAnnotations(image.Annotations).
 		WithSpec(batchv1apply.JobSpec().
 			WithTemplate(podTemplate).
 			WithTTLSecondsAfterFinished(ttl))
-	job.Spec.Template.ObjectMetaApplyConfiguration = metav1apply.ObjectMeta().WithLabels(setLabel(image.Name, image.Labels))
+	job.Spec.Template.ObjectMetaApplyConfiguration = metav1apply.ObjectMeta().WithLabels(setLabel(image.Name, image.Labels)).WithLabels(actorLabels(image))
 	return job, nil
 }
 
@@ -213,59 +266,168 @@ func genName(imageName string, cond buildv1beta1.ImageCondition) string {
 	default:
 		op = "unknown"
 	}
-	r := sha256.Sum256([]byte(fmt.Sprintf("%s-%s-%s", cond.TagPolicy, cond.Revision, cond.ResolvedRevision)))
+	key := fmt.Sprintf("%s-%s-%s", cond.TagPolicy, cond.Revision, cond.ResolvedRevision)
+	if cond.Target != "" {
+		key = fmt.Sprintf("%s-%s", key, cond.Target)
+	}
+	// each retry runs a new job, and the job of the first attempt keeps the name before retries
+	if cond.Attempts > 1 {
+		key = fmt.Sprintf("%s-%d", key, cond.Attempts)
+	}
+	r := sha256.Sum256([]byte(key))
 	h := hex.EncodeToString(r[:])
 	return fmt.Sprintf("%s-%s-%s", imageName, op, h[:7])
 }
 
-func cancelJob(ctx context.Context, c client.Client, image *buildv1beta1.Image, cond buildv1beta1.ImageCondition) error {
+func cancelJob(ctx context.Context, c client.Client, image *buildv1beta1.Image, cond buildv1beta1.ImageCondition, opts Options) error {
 	if cond.Status != buildv1beta1.ImageConditionStatusCanceled {
 		return nil
 	}
+	if target, ok := GetTarget(image.Spec.Targets, cond.Target); ok {
+		cond.Target = target.Name
+	}
 	p := v1.DeletePropagationBackground
 	return client.IgnoreNotFound(c.Delete(ctx, &batchv1.Job{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      genName(image.Name, cond),
-			Namespace: "oci-image-operator-system",
+			Namespace: opts.actorNamespace(image),
 		},
 	}, &client.DeleteOptions{
 		PropagationPolicy: &p,
 	}))
 }
 
-func uploadJob(image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, uploadedCondition buildv1beta1.ImageCondition) (*batchv1apply.JobApplyConfiguration, error) {
+func uploadJob(image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate, target buildv1beta1.ImageTarget, uploadedCondition buildv1beta1.ImageCondition, secrets map[string]*corev1.Secret, opts Options) (*batchv1apply.JobApplyConfiguration, error) {
 	revEnv := corev1apply.EnvVar().WithName("RESOLVED_REVISION").WithValue(uploadedCondition.ResolvedRevision)
-	podTemplate := corev1apply.PodTemplateSpec().WithSpec(corev1apply.PodSpec().
-		WithRestartPolicy(corev1.RestartPolicyOnFailure).
-		WithServiceAccountName("oci-image-operator-controller-manager").
-		WithVolumes(corev1apply.Volume().WithName("tmpdir").WithEmptyDir(corev1apply.EmptyDirVolumeSource())).
-		WithContainers(
-			actorContainer(image.Name, image.Namespace, &template.Spec.Upload, "upload").WithEnv(revEnv).WithEnv(toEnvVarConfiguration(image.Spec.Env)...),
-		))
-	// add sha256 from revision and tag policy
+	targetEnv := corev1apply.EnvVar().WithName("IMAGE_TARGET").WithValue(target.Name)
+	podSpec, err := actorPodSpec(&template.Spec.Upload, serviceAccountName(image, opts),
+		actorContainer(image.Name, image.Namespace, &template.Spec.Upload, "upload").
+			WithEnv(revEnv, targetEnv).
+			WithEnv(targetAuthEnvVar(target, buildv1beta1.ImageFlowPhaseUpload, &template.Spec.Upload, secrets)...).
+			WithEnv(toEnvVarConfiguration(image.Spec.Env)...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	podTemplate := corev1apply.PodTemplateSpec().WithSpec(podSpec.WithRestartPolicy(corev1.RestartPolicyOnFailure))
+	// add sha256 from revision, tag policy and target
+	uploadedCondition.Target = target.Name
 	name := genName(image.Name, uploadedCondition)
-	job := batchv1apply.Job(name, "oci-image-operator-system").
+	job := batchv1apply.Job(name, opts.actorNamespace(image)).
 		WithLabels(image.Labels).
+		WithLabels(actorLabels(image)).
+		WithOwnerReferences(opts.ownerReferences(image)...).
 		WithAnnotations(image.Annotations).
 		WithSpec(batchv1apply.JobSpec().
 			WithTemplate(podTemplate).
 			WithTTLSecondsAfterFinished(ttl))
-	job.Spec.Template.ObjectMetaApplyConfiguration = metav1apply.ObjectMeta().WithLabels(setLabel(image.Name, image.Labels))
+	job.Spec.Template.ObjectMetaApplyConfiguration = metav1apply.ObjectMeta().WithLabels(setLabel(image.Name, image.Labels)).WithLabels(actorLabels(image))
 	return job, nil
 }
 
+// actorPodSpec returns the pod spec which runs the actor container with the volumes, containers and pod options of the template.
+// The actor container runs with the restricted security context unless the template relaxes it.
+// Sidecars and init containers which set their own security context keep it as is, since a privileged sidecar
+// such as dind can't run with any of the restricted fields.
+func actorPodSpec(spec *buildv1beta1.ImageFlowTemplateSpecTemplate, serviceAccount string, container *corev1apply.ContainerApplyConfiguration) (*corev1apply.PodSpecApplyConfiguration, error) {
+	podSpec := corev1apply.PodSpec().
+		WithServiceAccountName(serviceAccount).
+		WithVolumes(corev1apply.Volume().WithName("tmpdir").WithEmptyDir(corev1apply.EmptyDirVolumeSource())).
+		WithContainers(container)
+	for i := range spec.Volumes {
+		podSpec.WithVolumes((*corev1apply.VolumeApplyConfiguration)(spec.Volumes[i].DeepCopy()))
+	}
+	for i := range spec.InitContainers {
+		podSpec.WithInitContainers((*corev1apply.ContainerApplyConfiguration)(spec.InitContainers[i].DeepCopy()))
+	}
+	for i := range spec.Sidecars {
+		podSpec.WithContainers((*corev1apply.ContainerApplyConfiguration)(spec.Sidecars[i].DeepCopy()))
+	}
+	sc, err := securityContext(&podSpec.Containers[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to merge security context")
+	}
+	podSpec.Containers[0].SecurityContext = sc
+	for _, containers := range [][]corev1apply.ContainerApplyConfiguration{podSpec.InitContainers, podSpec.Containers[1:]} {
+		for i := range containers {
+			if containers[i].SecurityContext != nil {
+				continue
+			}
+			if containers[i].SecurityContext, err = securityContext(&containers[i]); err != nil {
+				return nil, errors.Wrap(err, "failed to merge security context")
+			}
+		}
+	}
+	psc, err := podSecurityContext(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to merge pod security context")
+	}
+	podSpec.WithSecurityContext(psc)
+	if len(spec.NodeSelector) > 0 {
+		podSpec.WithNodeSelector(spec.NodeSelector)
+	}
+	if spec.PriorityClassName != "" {
+		podSpec.WithPriorityClassName(spec.PriorityClassName)
+	}
+	for _, s := range spec.ImagePullSecrets {
+		podSpec.WithImagePullSecrets(corev1apply.LocalObjectReference().WithName(s.Name))
+	}
+	for _, t := range spec.Tolerations {
+		toleration := &corev1apply.TolerationApplyConfiguration{}
+		if err := toApplyConfiguration(t, toleration); err != nil {
+			return nil, errors.Wrap(err, "failed to convert tolerations")
+		}
+		podSpec.WithTolerations(toleration)
+	}
+	if spec.Affinity != nil {
+		affinity := &corev1apply.AffinityApplyConfiguration{}
+		if err := toApplyConfiguration(spec.Affinity, affinity); err != nil {
+			return nil, errors.Wrap(err, "failed to convert affinity")
+		}
+		podSpec.WithAffinity(affinity)
+	}
+	return podSpec, nil
+}
+
+// toApplyConfiguration converts a core type to the apply configuration which has the same schema.
+func toApplyConfiguration(in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// defaultResources are the resources of the actor container when neither the template nor the actor has them.
+func defaultResources() *corev1apply.ResourceRequirementsApplyConfiguration {
+	return corev1apply.ResourceRequirements().
+		WithRequests(map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("50Mi"),
+		}).
+		WithLimits(map[corev1.ResourceName]resource.Quantity{
+			corev1.ResourceCPU:    resource.MustParse("300m"),
+			corev1.ResourceMemory: resource.MustParse("200Mi"),
+		})
+}
+
 func actorContainer(name, namespace string, spec *buildv1beta1.ImageFlowTemplateSpecTemplate, role string) *corev1apply.ContainerApplyConfiguration {
-	return (*corev1apply.ContainerApplyConfiguration)(spec.Actor.DeepCopy()).
+	container := (*corev1apply.ContainerApplyConfiguration)(spec.Actor.DeepCopy())
+	// resources of the template take precedence over the actor's
+	if spec.Resources != nil {
+		resources := corev1apply.ResourceRequirements()
+		if spec.Resources.Requests != nil {
+			resources.WithRequests(spec.Resources.Requests)
+		}
+		if spec.Resources.Limits != nil {
+			resources.WithLimits(spec.Resources.Limits)
+		}
+		container.WithResources(resources)
+	} else if container.Resources == nil {
+		container.WithResources(defaultResources())
+	}
+	return container.
 		WithName("main").
-		WithResources(corev1apply.ResourceRequirements().
-			WithRequests(map[corev1.ResourceName]resource.Quantity{
-				corev1.ResourceCPU:    resource.MustParse("50m"),
-				corev1.ResourceMemory: resource.MustParse("50Mi"),
-			}).
-			WithLimits(map[corev1.ResourceName]resource.Quantity{
-				corev1.ResourceCPU:    resource.MustParse("300m"),
-				corev1.ResourceMemory: resource.MustParse("200Mi"),
-			})).
 		WithArgs(role).
 		WithEnv(
 			corev1apply.EnvVar().WithName("IMAGE_NAME").WithValue(name),
@@ -311,11 +473,11 @@ func jobRunning(ctx context.Context, c client.Client, job *batchv1apply.JobApply
 	return existJob.Status.Active > 0, nil
 }
 func applyJob(ctx context.Context, c client.Client, job *batchv1apply.JobApplyConfiguration) error {
-	// wait for previous job
+	// wait for previous job, the image is reconciled again when it finishes
 	if running, err := jobRunning(ctx, c, job); err != nil {
 		return errors.Wrap(err, "failed to get Job status")
 	} else if running {
-		return fmt.Errorf("previous job is still running")
+		return &WaitingError{Reason: ReasonJobRunning, Message: fmt.Sprintf("previous job %s is still running", *job.Name), After: jobWaitInterval}
 	}
 	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(job)
 	if err != nil {
@@ -395,6 +557,16 @@ func GetCondition(conditions []buildv1beta1.ImageCondition, conditionType buildv
 	return ret
 }
 
+func GetConditionByTarget(conditions []buildv1beta1.ImageCondition, condType buildv1beta1.ImageConditionType, target string) []buildv1beta1.ImageCondition {
+	ret := []buildv1beta1.ImageCondition{}
+	for _, c := range GetCondition(conditions, condType) {
+		if c.Target == target {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
 func GetConditionByStatus(conditions []buildv1beta1.ImageCondition, condType buildv1beta1.ImageConditionType, status buildv1beta1.ImageConditionStatus) []buildv1beta1.ImageCondition {
 	ret := []buildv1beta1.ImageCondition{}
 	for _, c := range GetCondition(conditions, condType) {
@@ -410,11 +582,14 @@ Mark as Canceled with below strategy.
  1. checked Condition will be canceled when specified tagPolicy and revision are matched
  2. upload condition will be canceled when checked condition with specified tagPolicy and revision is exists and resolved revision of uploaded will be matched
 */
-func MarkUploadConditionAsCanceled(conditions []buildv1beta1.ImageCondition, tagPolicy buildv1beta1.ImageTagPolicyType, revision, resolvedRevision string) []buildv1beta1.ImageCondition {
+func MarkUploadConditionAsCanceled(conditions []buildv1beta1.ImageCondition, tagPolicy buildv1beta1.ImageTagPolicyType, target, revision, resolvedRevision string) []buildv1beta1.ImageCondition {
 	for i, c := range conditions {
+		if c.Target != target {
+			continue
+		}
 		if c.Type == buildv1beta1.ImageConditionTypeUploaded && c.Revision == revision && resolvedRevision != c.ResolvedRevision {
-			checked := GetConditionByRevision(conditions, buildv1beta1.ImageConditionTypeChecked, revision)
-			if checked.TagPolicy == tagPolicy && checked.ResolvedRevision == c.ResolvedRevision && checked.Status != buildv1beta1.ImageConditionStatusUnknown {
+			checked := GetConditionBy(conditions, buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageCondition{TagPolicy: tagPolicy, Revision: revision, Target: target})
+			if checked.ResolvedRevision == c.ResolvedRevision && checked.Status != buildv1beta1.ImageConditionStatusUnknown {
 				conditions[i].Status = buildv1beta1.ImageConditionStatusCanceled
 			}
 		}
@@ -465,7 +640,7 @@ func GetConditionByRevision(conditions []buildv1beta1.ImageCondition, condType b
 
 func GetConditionBy(conditions []buildv1beta1.ImageCondition, condType buildv1beta1.ImageConditionType, baseCondition buildv1beta1.ImageCondition) buildv1beta1.ImageCondition {
 	for _, c := range conditions {
-		if c.Type == condType && c.Revision == baseCondition.Revision && c.TagPolicy == baseCondition.TagPolicy {
+		if c.Type == condType && c.Revision == baseCondition.Revision && c.TagPolicy == baseCondition.TagPolicy && c.Target == baseCondition.Target {
 			return c
 		}
 	}
@@ -476,11 +651,12 @@ func GetConditionBy(conditions []buildv1beta1.ImageCondition, condType buildv1be
 		Revision:           baseCondition.Revision,
 		ResolvedRevision:   "",
 		TagPolicy:          baseCondition.TagPolicy,
+		Target:             baseCondition.Target,
 	}
 }
 func SetCondition(conditions []buildv1beta1.ImageCondition, condition buildv1beta1.ImageCondition) []buildv1beta1.ImageCondition {
 	for i, c := range conditions {
-		if c.Type == condition.Type && c.Revision == condition.Revision && c.TagPolicy == condition.TagPolicy {
+		if c.Type == condition.Type && c.Revision == condition.Revision && c.TagPolicy == condition.TagPolicy && c.Target == condition.Target {
 			conditions[i] = condition
 			return conditions
 		}
@@ -489,18 +665,19 @@ func SetCondition(conditions []buildv1beta1.ImageCondition, condition buildv1bet
 	return conditions
 }
 
-func UpdateCheckedCondition(conditions []buildv1beta1.ImageCondition, status buildv1beta1.ImageConditionStatus, revision, resolvedRevision string) []buildv1beta1.ImageCondition {
+func UpdateCheckedCondition(conditions []buildv1beta1.ImageCondition, status buildv1beta1.ImageConditionStatus, tagPolicy buildv1beta1.ImageTagPolicyType, target, revision, resolvedRevision string) []buildv1beta1.ImageCondition {
 	exists := false
 	now := v1.Now()
 	conds := GetCondition(conditions, buildv1beta1.ImageConditionTypeChecked)
 	for _, cond := range conds {
-		if cond.ResolvedRevision == resolvedRevision {
+		if cond.ResolvedRevision == resolvedRevision && cond.Target == target && matchTagPolicy(cond, tagPolicy) {
 			exists = true
 			cond.Revision = revision
 			if cond.Status != status {
+				prev := cond.Status
 				cond.Status = status
 				cond.LastTransitionTime = &now
-				return SetCondition(conditions, cond)
+				return SetCondition(conditions, resetReason(cond, prev))
 			}
 		}
 	}
@@ -508,7 +685,8 @@ func UpdateCheckedCondition(conditions []buildv1beta1.ImageCondition, status bui
 		conditions = append(conditions, buildv1beta1.ImageCondition{
 			Type:               buildv1beta1.ImageConditionTypeChecked,
 			Status:             status,
-			TagPolicy:          buildv1beta1.ImageTagPolicyTypeUnused,
+			TagPolicy:          tagPolicy,
+			Target:             target,
 			Revision:           revision,
 			ResolvedRevision:   resolvedRevision,
 			LastTransitionTime: &now,
@@ -517,34 +695,54 @@ func UpdateCheckedCondition(conditions []buildv1beta1.ImageCondition, status bui
 	return conditions
 
 }
-func UpdateUploadedCondition(conditions []buildv1beta1.ImageCondition, status buildv1beta1.ImageConditionStatus, revision, resolvedRevision string) []buildv1beta1.ImageCondition {
+func UpdateUploadedCondition(conditions []buildv1beta1.ImageCondition, status buildv1beta1.ImageConditionStatus, tagPolicy buildv1beta1.ImageTagPolicyType, target, revision, resolvedRevision string) []buildv1beta1.ImageCondition {
 	now := v1.Now()
 	exist := false
 	for i, c := range conditions {
 		if c.Revision == revision &&
 			c.Type == buildv1beta1.ImageConditionTypeUploaded &&
+			c.Target == target &&
+			matchTagPolicy(c, tagPolicy) &&
 			c.ResolvedRevision == resolvedRevision {
-			conditions[i].Status = status
+			prev := c.Status
+			c.Status = status
+			conditions[i] = resetReason(c, prev)
 			conditions[i].LastTransitionTime = &now
 			exist = true
 		}
 	}
 	if !exist {
+		// the tag rendered by detect is carried over from the checked condition
+		checked := GetConditionBy(conditions, buildv1beta1.ImageConditionTypeChecked, buildv1beta1.ImageCondition{TagPolicy: tagPolicy, Revision: revision, Target: target})
+		tag := ""
+		if checked.ResolvedRevision == resolvedRevision {
+			tag = checked.Tag
+		}
 		conditions = append(conditions, buildv1beta1.ImageCondition{
 			Type:               buildv1beta1.ImageConditionTypeUploaded,
 			Status:             status,
-			TagPolicy:          buildv1beta1.ImageTagPolicyTypeUnused,
+			TagPolicy:          tagPolicy,
+			Target:             target,
 			Revision:           revision,
 			ResolvedRevision:   resolvedRevision,
+			Tag:                tag,
 			LastTransitionTime: &now,
 		})
 	}
 	return conditions
 }
 
-func UpdateCondition(conditions []buildv1beta1.ImageCondition, condType buildv1beta1.ImageConditionType, status *buildv1beta1.ImageConditionStatus, tagPolicy buildv1beta1.ImageTagPolicyType, revision, resolvedRevision string) []buildv1beta1.ImageCondition {
+// matchTagPolicy returns true when the condition belongs to the tag policy.
+// unused matches any policy since older actors and conditions don't have it.
+func matchTagPolicy(cond buildv1beta1.ImageCondition, tagPolicy buildv1beta1.ImageTagPolicyType) bool {
+	return tagPolicy == buildv1beta1.ImageTagPolicyTypeUnused ||
+		cond.TagPolicy == buildv1beta1.ImageTagPolicyTypeUnused ||
+		cond.TagPolicy == tagPolicy
+}
+
+func UpdateCondition(conditions []buildv1beta1.ImageCondition, condType buildv1beta1.ImageConditionType, status *buildv1beta1.ImageConditionStatus, tagPolicy buildv1beta1.ImageTagPolicyType, target, revision, resolvedRevision string) []buildv1beta1.ImageCondition {
 	now := v1.Now()
-	cond := GetConditionBy(conditions, condType, buildv1beta1.ImageCondition{TagPolicy: tagPolicy, Revision: revision})
+	cond := GetConditionBy(conditions, condType, buildv1beta1.ImageCondition{TagPolicy: tagPolicy, Revision: revision, Target: target})
 	if cond.LastTransitionTime == nil {
 		if status != nil {
 			cond.Status = *status
@@ -579,6 +777,65 @@ func UpdateCondition(conditions []buildv1beta1.ImageCondition, condType buildv1b
 
 }
 
+// resetReason clears the reason and the message when the status of the condition changed from prev since they describe the previous status.
+func resetReason(cond buildv1beta1.ImageCondition, prev buildv1beta1.ImageConditionStatus) buildv1beta1.ImageCondition {
+	if cond.Status != prev {
+		cond.Reason, cond.Message = "", ""
+	}
+	return cond
+}
+
+// ImageTag returns the tag of the image built from the condition.
+// The tag rendered from TagTemplate is used if exists.
+// Name policies push the branch or tag name which moves to new commits, hash policies push the resolved commit.
+func ImageTag(cond buildv1beta1.ImageCondition) string {
+	if cond.Tag != "" {
+		return cond.Tag
+	}
+	switch cond.TagPolicy {
+	case buildv1beta1.ImageTagPolicyTypeBranchName, buildv1beta1.ImageTagPolicyTypeTagName, buildv1beta1.ImageTagPolicyTypeSemver:
+		return sanitizeTag(cond.Revision)
+	default:
+		return cond.ResolvedRevision
+	}
+}
+
+func sanitizeTag(tag string) string {
+	tag = invalidTagChars.ReplaceAllString(tag, "-")
+	tag = strings.TrimLeft(tag, ".-")
+	if len(tag) > 128 {
+		tag = tag[:128]
+	}
+	return tag
+}
+
+// GetTarget returns ImageTarget by name.
+// Conditions recorded before multiple targets were supported have no target, so they belong to the first target.
+func GetTarget(targets []buildv1beta1.ImageTarget, name string) (buildv1beta1.ImageTarget, bool) {
+	if name == "" && len(targets) > 0 {
+		return targets[0], true
+	}
+	for _, t := range targets {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return buildv1beta1.ImageTarget{}, false
+}
+
+// FillTarget sets the first target to conditions which have no target.
+func FillTarget(conditions []buildv1beta1.ImageCondition, targets []buildv1beta1.ImageTarget) []buildv1beta1.ImageCondition {
+	if len(targets) == 0 {
+		return conditions
+	}
+	for i, c := range conditions {
+		if c.Target == "" && c.Type.IsRevision() {
+			conditions[i].Target = targets[0].Name
+		}
+	}
+	return conditions
+}
+
 func InWorkDir(path string) string {
 	return fmt.Sprintf("%s/%s", actorWorkDir, path)
 }
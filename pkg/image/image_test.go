@@ -6,13 +6,19 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
 )
 
 func TestMarkUploadConditionAsCanceled(t *testing.T) {
 	type args struct {
 		conditions       []buildv1beta1.ImageCondition
 		tagPolicy        buildv1beta1.ImageTagPolicyType
+		target           string
 		revision         string
 		resolvedRevision string
 	}
@@ -183,13 +189,238 @@ func TestMarkUploadConditionAsCanceled(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "another_target",
+			args: args{
+				conditions: []buildv1beta1.ImageCondition{
+					{
+						Type:             buildv1beta1.ImageConditionTypeChecked,
+						Status:           buildv1beta1.ImageConditionStatusFalse,
+						TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+						Target:           "ghcr.io/test/a",
+						Revision:         "master",
+						ResolvedRevision: "tocancel",
+					},
+					{
+						Type:             buildv1beta1.ImageConditionTypeChecked,
+						Status:           buildv1beta1.ImageConditionStatusFalse,
+						TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+						Target:           "ghcr.io/test/b",
+						Revision:         "master",
+						ResolvedRevision: "nottocancel",
+					},
+				},
+				tagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+				target:           "ghcr.io/test/a",
+				revision:         "master",
+				resolvedRevision: "qwerty",
+			},
+			want: []buildv1beta1.ImageCondition{
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusCanceled,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/a",
+					Revision:         "master",
+					ResolvedRevision: "tocancel",
+				},
+				{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					Status:           buildv1beta1.ImageConditionStatusFalse,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/test/b",
+					Revision:         "master",
+					ResolvedRevision: "nottocancel",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := MarkUploadConditionAsCanceled(tt.args.conditions, tt.args.tagPolicy, tt.args.revision, tt.args.resolvedRevision); !reflect.DeepEqual(got, tt.want) {
+			if got := MarkUploadConditionAsCanceled(tt.args.conditions, tt.args.tagPolicy, tt.args.target, tt.args.revision, tt.args.resolvedRevision); !reflect.DeepEqual(got, tt.want) {
 				t.Error("MarkUploadConditionAsCanceled()")
 				fmt.Println(cmp.Diff(got, tt.want))
 			}
 		})
 	}
 }
+
+func Test_genName(t *testing.T) {
+	type args struct {
+		imageName string
+		cond      buildv1beta1.ImageCondition
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "without_target",
+			args: args{
+				imageName: "test",
+				cond: buildv1beta1.ImageCondition{
+					Type:             buildv1beta1.ImageConditionTypeChecked,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Revision:         "master",
+					ResolvedRevision: "test12345",
+				},
+			},
+			want: "test-check-9ac55c1",
+		},
+		{
+			name: "with_target",
+			args: args{
+				imageName: "test",
+				cond: buildv1beta1.ImageCondition{
+					Type:             buildv1beta1.ImageConditionTypeUploaded,
+					TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+					Target:           "ghcr.io/takutakahashi/test",
+					Revision:         "master",
+					ResolvedRevision: "test12345",
+				},
+			},
+			want: "test-upload-d2ffe19",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := genName(tt.args.imageName, tt.args.cond); got != tt.want {
+				t.Errorf("genName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateUploadedCondition(t *testing.T) {
+	conditions := []buildv1beta1.ImageCondition{
+		{
+			Type:             buildv1beta1.ImageConditionTypeChecked,
+			Status:           buildv1beta1.ImageConditionStatusTrue,
+			TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+			Target:           "target",
+			Revision:         "main",
+			ResolvedRevision: "aaa",
+			Tag:              "main-aaa",
+		},
+	}
+	want := append(conditions, buildv1beta1.ImageCondition{
+		Type:             buildv1beta1.ImageConditionTypeUploaded,
+		Status:           buildv1beta1.ImageConditionStatusFalse,
+		TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+		Target:           "target",
+		Revision:         "main",
+		ResolvedRevision: "aaa",
+		Tag:              "main-aaa",
+	})
+	got := UpdateUploadedCondition(conditions, buildv1beta1.ImageConditionStatusFalse, buildv1beta1.ImageTagPolicyTypeBranchHash, "target", "main", "aaa")
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(buildv1beta1.ImageCondition{}, "LastTransitionTime")); diff != "" {
+		t.Errorf("UpdateUploadedCondition() diff: %s", diff)
+	}
+}
+
+func TestActorContainer_Resources(t *testing.T) {
+	resources := func(cpu string) corev1.ResourceList {
+		return corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}
+	}
+	tests := []struct {
+		name string
+		spec buildv1beta1.ImageFlowTemplateSpecTemplate
+		want *corev1apply.ResourceRequirementsApplyConfiguration
+	}{
+		{
+			name: "default",
+			spec: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: &buildv1beta1.ContainerApplyConfiguration{}},
+			want: defaultResources(),
+		},
+		{
+			name: "actor",
+			spec: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: &buildv1beta1.ContainerApplyConfiguration{
+				Resources: corev1apply.ResourceRequirements().WithLimits(resources("2")),
+			}},
+			want: corev1apply.ResourceRequirements().WithLimits(resources("2")),
+		},
+		{
+			name: "template",
+			spec: buildv1beta1.ImageFlowTemplateSpecTemplate{
+				Actor: &buildv1beta1.ContainerApplyConfiguration{
+					Resources: corev1apply.ResourceRequirements().WithLimits(resources("2")),
+				},
+				Resources: &corev1.ResourceRequirements{Requests: resources("1"), Limits: resources("4")},
+			},
+			want: corev1apply.ResourceRequirements().WithRequests(resources("1")).WithLimits(resources("4")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := actorContainer("test", "default", &tt.spec, "upload")
+			if !equality.Semantic.DeepEqual(tt.want, got.Resources) {
+				t.Errorf("resources = %v, want %v", got.Resources, tt.want)
+			}
+		})
+	}
+}
+
+func TestActorPodSpec(t *testing.T) {
+	spec := &buildv1beta1.ImageFlowTemplateSpecTemplate{
+		Actor:             &buildv1beta1.ContainerApplyConfiguration{},
+		NodeSelector:      map[string]string{"kubernetes.io/arch": "amd64"},
+		PriorityClassName: "builder",
+		ImagePullSecrets:  []corev1.LocalObjectReference{{Name: "pull"}},
+		Tolerations: []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "build", Effect: corev1.TaintEffectNoSchedule},
+		},
+		Affinity: &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"build"}}},
+					}},
+				},
+			},
+		},
+	}
+	got, err := actorPodSpec(spec, "test-actor", actorContainer("test", "default", spec, "upload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NodeSelector["kubernetes.io/arch"] != "amd64" || *got.PriorityClassName != "builder" || *got.ImagePullSecrets[0].Name != "pull" {
+		t.Errorf("pod spec = %v", got)
+	}
+	if len(got.Tolerations) != 1 || *got.Tolerations[0].Key != "dedicated" || *got.Tolerations[0].Effect != corev1.TaintEffectNoSchedule {
+		t.Errorf("tolerations = %v", got.Tolerations)
+	}
+	if got.Affinity == nil || got.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0].Values[0] != "build" {
+		t.Errorf("affinity = %v", got.Affinity)
+	}
+}
+
+func TestActorPodSpec_VolumesAndContainers(t *testing.T) {
+	actor := corev1apply.Container().WithVolumeMounts(corev1apply.VolumeMount().WithName("cache").WithMountPath("/cache"))
+	spec := &buildv1beta1.ImageFlowTemplateSpecTemplate{
+		Actor:          (*buildv1beta1.ContainerApplyConfiguration)(actor),
+		Volumes:        []buildv1beta1.VolumeApplyConfiguration{buildv1beta1.VolumeApplyConfiguration(*corev1apply.Volume().WithName("cache").WithEmptyDir(corev1apply.EmptyDirVolumeSource()))},
+		InitContainers: []buildv1beta1.ContainerApplyConfiguration{buildv1beta1.ContainerApplyConfiguration(*corev1apply.Container().WithName("init").WithImage("busybox"))},
+		Sidecars:       []buildv1beta1.ContainerApplyConfiguration{buildv1beta1.ContainerApplyConfiguration(*corev1apply.Container().WithName("dind").WithImage("docker:dind"))},
+	}
+	got, err := actorPodSpec(spec, "test-actor", actorContainer("test", "default", spec, "upload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Volumes) != 2 || *got.Volumes[0].Name != "tmpdir" || *got.Volumes[1].Name != "cache" {
+		t.Errorf("volumes = %v", got.Volumes)
+	}
+	if len(got.InitContainers) != 1 || *got.InitContainers[0].Name != "init" {
+		t.Errorf("init containers = %v", got.InitContainers)
+	}
+	if len(got.Containers) != 2 || *got.Containers[0].Name != "main" || *got.Containers[1].Name != "dind" {
+		t.Errorf("containers = %v", got.Containers)
+	}
+	mounts := []string{}
+	for _, m := range got.Containers[0].VolumeMounts {
+		mounts = append(mounts, *m.Name)
+	}
+	if len(mounts) != 2 || mounts[0] != "cache" || mounts[1] != "tmpdir" {
+		t.Errorf("volume mounts = %v", mounts)
+	}
+}
@@ -0,0 +1,166 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ImageLabel is the label of actor workloads which has the name of the image.
+	ImageLabel = "build.takutakahashi.dev/image"
+	// ImageNamespaceLabel is the label of actor workloads which has the namespace of the image.
+	// Workloads are mapped to the image by labels since they can't be owned by the image in the other namespace.
+	ImageNamespaceLabel = "build.takutakahashi.dev/image-namespace"
+
+	ReasonJobFailed           = "JobFailed"
+	ReasonJobDeadlineExceeded = "DeadlineExceeded"
+	ReasonJobNoResult         = "JobCompletedWithoutResult"
+
+	// logTailLines is the number of log lines of the failed actor which are recorded in the condition.
+	logTailLines = 20
+	// maxLogLength limits the logs in the condition so that the status stays small.
+	maxLogLength = 1024
+)
+
+// actorLabels returns the labels of actor workloads of the image.
+func actorLabels(image *buildv1beta1.Image) map[string]string {
+	return map[string]string{
+		ImageLabel:          image.Name,
+		ImageNamespaceLabel: image.Namespace,
+	}
+}
+
+// UpdateJobConditions maps outcomes of check and upload jobs onto the conditions which they run for.
+// Conditions of failed jobs become Failed with the termination reason of the actor and the end of its logs.
+// Conditions of completed jobs become Failed when the actor didn't update them.
+// Pods are read with cs only when a job fails, and they are not read when cs is nil.
+func UpdateJobConditions(ctx context.Context, c client.Client, cs kubernetes.Interface, image *buildv1beta1.Image, opts Options) (*buildv1beta1.Image, error) {
+	for i, cond := range image.Status.Conditions {
+		if cond.Status != buildv1beta1.ImageConditionStatusFalse ||
+			(cond.Type != buildv1beta1.ImageConditionTypeChecked && cond.Type != buildv1beta1.ImageConditionTypeUploaded) {
+			continue
+		}
+		if target, ok := GetTarget(image.Spec.Targets, cond.Target); ok {
+			cond.Target = target.Name
+		}
+		job := &batchv1.Job{}
+		if err := c.Get(ctx, client.ObjectKey{Name: genName(image.Name, cond), Namespace: opts.actorNamespace(image)}, job); apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrap(err, "failed to get job")
+		}
+		reason, message := "", ""
+		if failed := jobCondition(job, batchv1.JobFailed); failed != nil {
+			reason = ReasonJobFailed
+			if failed.Reason == "DeadlineExceeded" {
+				reason = ReasonJobDeadlineExceeded
+			}
+			message = fmt.Sprintf("job %s failed: %s: %s", job.Name, failed.Reason, failed.Message)
+			// the failure is recorded without the pod when it can't be read
+			if detail, err := failedPodDetail(ctx, cs, job); err != nil {
+				logrus.Warnf("failed to get pods of job %s: %v", job.Name, err)
+			} else if detail != "" {
+				message = fmt.Sprintf("%s; %s", message, detail)
+			}
+		} else if jobCondition(job, batchv1.JobComplete) != nil {
+			reason = ReasonJobNoResult
+			message = fmt.Sprintf("job %s completed without updating the condition", job.Name)
+		} else {
+			continue
+		}
+		now := v1.Now()
+		image.Status.Conditions[i].Status = buildv1beta1.ImageConditionStatusFailed
+		image.Status.Conditions[i].Reason = reason
+		image.Status.Conditions[i].Message = message
+		image.Status.Conditions[i].LastTransitionTime = &now
+	}
+	return image, nil
+}
+
+// jobCondition returns the condition of the type if it is True.
+func jobCondition(job *batchv1.Job, t batchv1.JobConditionType) *batchv1.JobCondition {
+	for i, cond := range job.Status.Conditions {
+		if cond.Type == t && cond.Status == corev1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// failedPodDetail describes the last termination of the actor container in the latest pod of the job and the end of its logs.
+func failedPodDetail(ctx context.Context, cs kubernetes.Interface, job *batchv1.Job) (string, error) {
+	if cs == nil {
+		return "", nil
+	}
+	pods, err := latestPods(ctx, cs, job.Namespace, labels.Set{"controller-uid": string(job.UID)})
+	if err != nil || len(pods) == 0 {
+		return "", err
+	}
+	for _, status := range pods[0].Status.ContainerStatuses {
+		if status.Name == "main" {
+			return terminationDetail(ctx, cs, &pods[0], status), nil
+		}
+	}
+	return "", nil
+}
+
+// latestPods returns pods which match the labels from the latest.
+func latestPods(ctx context.Context, cs kubernetes.Interface, namespace string, set labels.Set) ([]corev1.Pod, error) {
+	pods, err := cs.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{LabelSelector: labels.SelectorFromSet(set).String()})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[j].CreationTimestamp.Before(&pods.Items[i].CreationTimestamp)
+	})
+	return pods.Items, nil
+}
+
+// terminationDetail describes the last termination of the container and the end of its logs.
+// It is empty when the container has never terminated.
+func terminationDetail(ctx context.Context, cs kubernetes.Interface, pod *corev1.Pod, status corev1.ContainerStatus) string {
+	// containers are restarted in the same pod with OnFailure, so the last state has the failure while it is waiting
+	terminated, previous := status.State.Terminated, false
+	if terminated == nil {
+		terminated, previous = status.LastTerminationState.Terminated, true
+	}
+	if terminated == nil {
+		return ""
+	}
+	ret := fmt.Sprintf("container %s in pod %s terminated with %s (exit code %d)", status.Name, pod.Name, terminated.Reason, terminated.ExitCode)
+	if terminated.Message != "" {
+		ret = fmt.Sprintf("%s: %s", ret, terminated.Message)
+	}
+	logs, err := cs.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: status.Name,
+		Previous:  previous,
+		TailLines: pointer.Int64(logTailLines),
+	}).DoRaw(ctx)
+	// logs may be gone with the node, so the termination is recorded without them
+	if err == nil && len(logs) > 0 {
+		ret = fmt.Sprintf("%s\n%s", ret, tail(strings.TrimRight(string(logs), "\n"), maxLogLength))
+	}
+	return ret
+}
+
+// tail returns about the last n bytes of s since the end of logs describes the failure.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "..." + strings.ToValidUTF8(s[len(s)-n:], "")
+}
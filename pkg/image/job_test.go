@@ -0,0 +1,155 @@
+package image
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUpdateJobConditions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-check-pod", Namespace: DefaultActorNamespace, Labels: map[string]string{"controller-uid": "job-uid"}},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main", LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}}},
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		conditions []batchv1.JobCondition
+		status     buildv1beta1.ImageConditionStatus
+		reason     string
+		message    []string
+	}{
+		{
+			name:   "running",
+			status: buildv1beta1.ImageConditionStatusFalse,
+		},
+		{
+			name: "failed",
+			conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"},
+			},
+			status:  buildv1beta1.ImageConditionStatusFailed,
+			reason:  ReasonJobFailed,
+			message: []string{"BackoffLimitExceeded", "terminated with Error (exit code 1)", "fake logs"},
+		},
+		{
+			name: "deadline_exceeded",
+			conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "DeadlineExceeded", Message: "Job was active longer than specified deadline"},
+			},
+			status:  buildv1beta1.ImageConditionStatusFailed,
+			reason:  ReasonJobDeadlineExceeded,
+			message: []string{"DeadlineExceeded"},
+		},
+		{
+			name: "complete",
+			conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+			},
+			status: buildv1beta1.ImageConditionStatusFailed,
+			reason: ReasonJobNoResult,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := newOptionsTestImage()
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: genName(image.Name, image.Status.Conditions[0]), Namespace: DefaultActorNamespace, UID: "job-uid"},
+				Status:     batchv1.JobStatus{Conditions: tt.conditions},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(job).Build()
+			got, err := UpdateJobConditions(context.Background(), c, kubefake.NewSimpleClientset(pod), image, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cond := got.Status.Conditions[0]
+			if cond.Status != tt.status || cond.Reason != tt.reason {
+				t.Errorf("condition = %s/%s, want %s/%s", cond.Status, cond.Reason, tt.status, tt.reason)
+			}
+			for _, m := range tt.message {
+				if !strings.Contains(cond.Message, m) {
+					t.Errorf("message %q doesn't contain %q", cond.Message, m)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateJobConditions_WithoutJob(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	image := newOptionsTestImage()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	got, err := UpdateJobConditions(context.Background(), c, nil, image, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Conditions[0].Status != buildv1beta1.ImageConditionStatusFalse {
+		t.Errorf("condition without job is updated: %v", got.Status.Conditions[0])
+	}
+}
+
+func TestTail(t *testing.T) {
+	if got := tail("abc", 3); got != "abc" {
+		t.Errorf("tail() = %s, want abc", got)
+	}
+	if got := tail("abcdef", 3); got != "...def" {
+		t.Errorf("tail() = %s, want ...def", got)
+	}
+}
+
+func TestActorPodLabels(t *testing.T) {
+	actor := &buildv1beta1.ContainerApplyConfiguration{}
+	template := &buildv1beta1.ImageFlowTemplate{
+		Spec: buildv1beta1.ImageFlowTemplateSpec{
+			Detect: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor},
+			Check:  buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor},
+			Upload: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor},
+		},
+	}
+	image := &buildv1beta1.Image{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec:       buildv1beta1.ImageSpec{Targets: []buildv1beta1.ImageTarget{{Name: "ghcr.io/takutakahashi/test"}}},
+	}
+	deploy, err := detectDeployment(image, template, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond := buildv1beta1.ImageCondition{Type: buildv1beta1.ImageConditionTypeChecked, Revision: "master", ResolvedRevision: "aaa"}
+	check, err := checkJob(image, template, image.Spec.Targets[0], cond, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond.Type = buildv1beta1.ImageConditionTypeUploaded
+	upload, err := uploadJob(image, template, image.Spec.Targets[0], cond, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, labels := range map[string]map[string]string{
+		"detect": deploy.Spec.Template.Labels,
+		"check":  check.Spec.Template.Labels,
+		"upload": upload.Spec.Template.Labels,
+	} {
+		if labels[ImageLabel] != "test" || labels[ImageNamespaceLabel] != "default" {
+			t.Errorf("%s pod labels = %v", name, labels)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1apply "k8s.io/client-go/applyconfigurations/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultActorNamespace is the namespace where actors run unless they run in the namespace of the Image.
+	DefaultActorNamespace = "oci-image-operator-system"
+	// DefaultDetectInterval is the interval of polls of the detect actors in this repository.
+	DefaultDetectInterval = time.Minute
+	// DefaultDetectStaleIntervals is the number of intervals without a successful poll after which the detector is stale.
+	DefaultDetectStaleIntervals = 5
+	// DetectRecordInterval is the age of LastDetectTime after which the detect actor records a poll which changed no condition.
+	// Polls are not recorded every interval since each status update reconciles the Image.
+	DetectRecordInterval = 3 * time.Minute
+)
+
+// Options configures where actor workloads run.
+type Options struct {
+	// Namespace is the namespace where actors run. DefaultActorNamespace is used when it is empty.
+	Namespace string
+	// InImageNamespace runs actors in the namespace of the Image with controller owner references.
+	// Workloads which were created in Namespace before are deleted on the next reconcile.
+	InImageNamespace bool
+	// DetectInterval is the interval of polls of the detect actor. DefaultDetectInterval is used when it is zero.
+	DetectInterval time.Duration
+	// DetectStaleIntervals is the number of intervals without a successful poll after which the detector is stale.
+	// DefaultDetectStaleIntervals is used when it is zero.
+	DetectStaleIntervals int
+}
+
+// DetectStaleAfter returns the duration without a successful poll after which the detector is stale.
+// It includes DetectRecordInterval since LastDetectTime lags behind the last poll by up to it.
+func (o Options) DetectStaleAfter() time.Duration {
+	interval, n := o.DetectInterval, o.DetectStaleIntervals
+	if interval == 0 {
+		interval = DefaultDetectInterval
+	}
+	if n == 0 {
+		n = DefaultDetectStaleIntervals
+	}
+	return interval*time.Duration(n) + DetectRecordInterval
+}
+
+// actorNamespace returns the namespace where the actors of the image run.
+func (o Options) actorNamespace(image *buildv1beta1.Image) string {
+	if o.InImageNamespace {
+		return image.Namespace
+	}
+	if o.Namespace == "" {
+		return DefaultActorNamespace
+	}
+	return o.Namespace
+}
+
+// staleNamespace returns the namespace where the actors of the image ran with the other mode.
+// It is empty when both modes use the same namespace.
+func (o Options) staleNamespace(image *buildv1beta1.Image) string {
+	stale := Options{Namespace: o.Namespace, InImageNamespace: !o.InImageNamespace}.actorNamespace(image)
+	if stale == o.actorNamespace(image) {
+		return ""
+	}
+	return stale
+}
+
+// ownerReferences returns the controller reference to the image when actors run in its namespace.
+// Owner references across namespaces are not allowed, so nothing is returned otherwise.
+func (o Options) ownerReferences(image *buildv1beta1.Image) []*metav1apply.OwnerReferenceApplyConfiguration {
+	if !o.InImageNamespace || image.UID == "" {
+		return nil
+	}
+	return []*metav1apply.OwnerReferenceApplyConfiguration{
+		metav1apply.OwnerReference().
+			WithAPIVersion(buildv1beta1.GroupVersion.String()).
+			WithKind("Image").
+			WithName(image.Name).
+			WithUID(image.UID).
+			WithController(true).
+			WithBlockOwnerDeletion(true),
+	}
+}
+
+// migrateWorkloads deletes the detect deployment and in-progress jobs which were created with the other mode.
+// They are created again in the current namespace by Ensure.
+func migrateWorkloads(ctx context.Context, c client.Client, image *buildv1beta1.Image, opts Options) error {
+	namespace := opts.staleNamespace(image)
+	if namespace == "" {
+		return nil
+	}
+	objs := []client.Object{&appsv1.Deployment{ObjectMeta: v1.ObjectMeta{Name: fmt.Sprintf("%s-detect", image.Name), Namespace: namespace}}}
+	for _, cond := range image.Status.Conditions {
+		if (cond.Type != buildv1beta1.ImageConditionTypeChecked && cond.Type != buildv1beta1.ImageConditionTypeUploaded) ||
+			cond.Status != buildv1beta1.ImageConditionStatusFalse {
+			continue
+		}
+		if target, ok := GetTarget(image.Spec.Targets, cond.Target); ok {
+			cond.Target = target.Name
+		}
+		objs = append(objs, &batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: genName(image.Name, cond), Namespace: namespace}})
+	}
+	p := v1.DeletePropagationBackground
+	for _, obj := range objs {
+		// get from the cache first not to call delete on every reconcile
+		if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if !actorOf(image, obj) {
+			continue
+		}
+		if err := c.Delete(ctx, obj, &client.DeleteOptions{PropagationPolicy: &p}); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// actorOf returns true if obj is an actor workload of the image, not an unrelated object with the same name.
+// Workloads in the namespace of the image must be controlled by it.
+func actorOf(image *buildv1beta1.Image, obj client.Object) bool {
+	if obj.GetNamespace() == image.Namespace && !v1.IsControlledBy(obj, image) {
+		return false
+	}
+	var labels map[string]string
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		labels = o.Spec.Template.Labels
+	case *batchv1.Job:
+		labels = o.Spec.Template.Labels
+	}
+	return labels["build.takutakahashi.dev/image"] == image.Name
+}
@@ -0,0 +1,173 @@
+package image
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newOptionsTestImage() *buildv1beta1.Image {
+	return &buildv1beta1.Image{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "uid"},
+		Spec: buildv1beta1.ImageSpec{
+			Targets: []buildv1beta1.ImageTarget{{Name: "ghcr.io/takutakahashi/test"}},
+		},
+		Status: buildv1beta1.ImageStatus{
+			Conditions: []buildv1beta1.ImageCondition{
+				{Type: buildv1beta1.ImageConditionTypeChecked, Status: buildv1beta1.ImageConditionStatusFalse, Target: "ghcr.io/takutakahashi/test", Revision: "master", ResolvedRevision: "aaa"},
+			},
+		},
+	}
+}
+
+func TestOptions(t *testing.T) {
+	image := newOptionsTestImage()
+	tests := []struct {
+		name      string
+		opts      Options
+		namespace string
+		stale     string
+		owned     bool
+	}{
+		{
+			name:      "default",
+			opts:      Options{},
+			namespace: DefaultActorNamespace,
+			stale:     "default",
+		},
+		{
+			name:      "namespace",
+			opts:      Options{Namespace: "actors"},
+			namespace: "actors",
+			stale:     "default",
+		},
+		{
+			name:      "image_namespace",
+			opts:      Options{Namespace: DefaultActorNamespace, InImageNamespace: true},
+			namespace: "default",
+			stale:     DefaultActorNamespace,
+			owned:     true,
+		},
+		{
+			name:      "same_namespace",
+			opts:      Options{Namespace: "default", InImageNamespace: true},
+			namespace: "default",
+			stale:     "",
+			owned:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.actorNamespace(image); got != tt.namespace {
+				t.Errorf("actorNamespace() = %s, want %s", got, tt.namespace)
+			}
+			if got := tt.opts.staleNamespace(image); got != tt.stale {
+				t.Errorf("staleNamespace() = %s, want %s", got, tt.stale)
+			}
+			refs := tt.opts.ownerReferences(image)
+			if (len(refs) == 1) != tt.owned {
+				t.Fatalf("ownerReferences() = %v, want owned %v", refs, tt.owned)
+			}
+			if tt.owned && (*refs[0].Kind != "Image" || *refs[0].UID != image.UID || !*refs[0].Controller) {
+				t.Errorf("ownerReferences() = %v", refs[0])
+			}
+		})
+	}
+}
+
+func TestOptions_DetectStaleAfter(t *testing.T) {
+	if got := (Options{}).DetectStaleAfter(); got != 8*time.Minute {
+		t.Errorf("DetectStaleAfter() = %s, want 8m", got)
+	}
+	if got := (Options{DetectInterval: 10 * time.Second, DetectStaleIntervals: 3}).DetectStaleAfter(); got != 3*time.Minute+30*time.Second {
+		t.Errorf("DetectStaleAfter() = %s, want 3m30s", got)
+	}
+}
+
+func TestMigrateWorkloads(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := buildv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	image := newOptionsTestImage()
+	template := corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: setLabel(image.Name, nil)}}
+	owner := metav1.OwnerReference{APIVersion: buildv1beta1.GroupVersion.String(), Kind: "Image", Name: image.Name, UID: image.UID, Controller: pointer.Bool(true)}
+	checkName := genName(image.Name, image.Status.Conditions[0])
+	tests := []struct {
+		name    string
+		opts    Options
+		objs    []client.Object
+		deleted []client.Object
+		kept    []client.Object
+	}{
+		{
+			name: "to_image_namespace",
+			opts: Options{InImageNamespace: true},
+			objs: []client.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-detect", Namespace: DefaultActorNamespace}, Spec: appsv1.DeploymentSpec{Template: template}},
+				&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: checkName, Namespace: DefaultActorNamespace}, Spec: batchv1.JobSpec{Template: template}},
+				&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "test-check-done", Namespace: DefaultActorNamespace}, Spec: batchv1.JobSpec{Template: template}},
+			},
+			deleted: []client.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-detect", Namespace: DefaultActorNamespace}},
+				&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: checkName, Namespace: DefaultActorNamespace}},
+			},
+			kept: []client.Object{
+				&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "test-check-done", Namespace: DefaultActorNamespace}},
+			},
+		},
+		{
+			name: "to_actor_namespace",
+			opts: Options{},
+			objs: []client.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-detect", Namespace: "default", OwnerReferences: []metav1.OwnerReference{owner}}, Spec: appsv1.DeploymentSpec{Template: template}},
+			},
+			deleted: []client.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-detect", Namespace: "default"}},
+			},
+		},
+		{
+			name: "not_owned",
+			opts: Options{},
+			objs: []client.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-detect", Namespace: "default"}, Spec: appsv1.DeploymentSpec{Template: template}},
+			},
+			kept: []client.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test-detect", Namespace: "default"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objs...).Build()
+			if err := migrateWorkloads(context.Background(), c, image, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			for _, obj := range tt.deleted {
+				if err := c.Get(context.Background(), client.ObjectKeyFromObject(obj), obj); !apierrors.IsNotFound(err) {
+					t.Errorf("%s is not deleted: %v", obj.GetName(), err)
+				}
+			}
+			for _, obj := range tt.kept {
+				if err := c.Get(context.Background(), client.ObjectKeyFromObject(obj), obj); err != nil {
+					t.Errorf("%s is deleted: %v", obj.GetName(), err)
+				}
+			}
+		})
+	}
+}
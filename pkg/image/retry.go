@@ -0,0 +1,108 @@
+package image
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ReasonRetrying           = "Retrying"
+	ReasonRetryLimitExceeded = "RetryLimitExceeded"
+
+	// maxRetryBackoff caps the backoff which doubles on each retry.
+	maxRetryBackoff = time.Hour
+)
+
+// RetryUploads retries failed uploads with the retry policy of the image.
+// A failed upload becomes False with the next attempt when its backoff has passed, and Ensure runs the upload job of the attempt.
+// Uploads which used all attempts are marked as permanently failed with RetryLimitExceeded.
+// It returns the duration until the next retry, which is zero when no retry is waiting.
+func RetryUploads(image *buildv1beta1.Image, now time.Time) time.Duration {
+	policy := image.Spec.RetryPolicy
+	if policy == nil {
+		return 0
+	}
+	var next time.Duration
+	for i, cond := range image.Status.Conditions {
+		if cond.Type != buildv1beta1.ImageConditionTypeUploaded || cond.Status != buildv1beta1.ImageConditionStatusFailed ||
+			cond.Reason == ReasonRetryLimitExceeded || superseded(image.Status.Conditions, cond) {
+			continue
+		}
+		attempts := uploadAttempts(cond)
+		if attempts >= policy.MaxAttempts {
+			image.Status.Conditions[i].Reason = ReasonRetryLimitExceeded
+			image.Status.Conditions[i].Message = withCause(fmt.Sprintf("upload failed %d times", attempts), cond.Message)
+			continue
+		}
+		failedAt := now
+		if cond.LastTransitionTime != nil {
+			failedAt = cond.LastTransitionTime.Time
+		}
+		if wait := failedAt.Add(retryBackoff(image, cond, attempts)).Sub(now); wait > 0 {
+			if next == 0 || wait < next {
+				next = wait
+			}
+			continue
+		}
+		t := v1.NewTime(now)
+		image.Status.Conditions[i].Status = buildv1beta1.ImageConditionStatusFalse
+		image.Status.Conditions[i].Attempts = attempts + 1
+		image.Status.Conditions[i].Reason = ReasonRetrying
+		image.Status.Conditions[i].Message = withCause(fmt.Sprintf("attempt %d of %d", attempts+1, policy.MaxAttempts), cond.Message)
+		image.Status.Conditions[i].LastTransitionTime = &t
+	}
+	return next
+}
+
+// uploadAttempts returns the number of uploads started for the condition. Conditions before retries count as the first.
+func uploadAttempts(cond buildv1beta1.ImageCondition) int32 {
+	if cond.Attempts < 1 {
+		return 1
+	}
+	return cond.Attempts
+}
+
+// retryBackoff returns the delay after the failure of the attempt.
+// The jitter is derived from the condition so that the delay is the same on every reconcile.
+func retryBackoff(image *buildv1beta1.Image, cond buildv1beta1.ImageCondition, attempts int32) time.Duration {
+	policy := image.Spec.RetryPolicy
+	backoff := buildv1beta1.DefaultRetryBackoff
+	if policy.Backoff != nil {
+		backoff = policy.Backoff.Duration
+	}
+	for i := int32(1); i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	if policy.Jitter <= 0 {
+		return backoff
+	}
+	h := fnv.New32a()
+	h.Write([]byte(genName(image.Name, cond)))
+	return backoff + time.Duration(float64(backoff)*float64(policy.Jitter)/100*float64(h.Sum32()%1000)/1000)
+}
+
+// superseded returns true if a later upload of the same revision and target exists.
+func superseded(conditions []buildv1beta1.ImageCondition, cond buildv1beta1.ImageCondition) bool {
+	for i := range conditions {
+		c := &conditions[i]
+		if c.Type == buildv1beta1.ImageConditionTypeUploaded && c.Target == cond.Target && c.TagPolicy == cond.TagPolicy &&
+			c.Revision == cond.Revision && c.ResolvedRevision != cond.ResolvedRevision && !newer(&cond, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func withCause(message, cause string) string {
+	if cause == "" {
+		return message
+	}
+	return fmt.Sprintf("%s: %s", message, cause)
+}
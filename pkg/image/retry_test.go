@@ -0,0 +1,125 @@
+package image
+
+import (
+	"testing"
+	"time"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRetryUploads(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	failedAt := metav1.NewTime(now.Add(-90 * time.Second))
+	later := metav1.NewTime(now.Add(-time.Second))
+	policy := &buildv1beta1.ImageRetryPolicy{MaxAttempts: 3, Backoff: &metav1.Duration{Duration: time.Minute}}
+	failed := buildv1beta1.ImageCondition{
+		Type:               buildv1beta1.ImageConditionTypeUploaded,
+		Status:             buildv1beta1.ImageConditionStatusFailed,
+		Target:             "ghcr.io/takutakahashi/test",
+		Revision:           "master",
+		ResolvedRevision:   "aaa",
+		Reason:             ReasonJobFailed,
+		Message:            "job failed",
+		LastTransitionTime: &failedAt,
+	}
+	tests := []struct {
+		name     string
+		policy   *buildv1beta1.ImageRetryPolicy
+		attempts int32
+		others   []buildv1beta1.ImageCondition
+		want     buildv1beta1.ImageCondition
+		wait     time.Duration
+	}{
+		{
+			name:   "no_policy",
+			policy: nil,
+			want:   failed,
+		},
+		{
+			name:   "retry",
+			policy: policy,
+			want: buildv1beta1.ImageCondition{
+				Status:   buildv1beta1.ImageConditionStatusFalse,
+				Attempts: 2,
+				Reason:   ReasonRetrying,
+				Message:  "attempt 2 of 3: job failed",
+			},
+		},
+		{
+			name:     "waiting_backoff",
+			policy:   policy,
+			attempts: 2,
+			want:     failed,
+			wait:     30 * time.Second,
+		},
+		{
+			name:     "limit_exceeded",
+			policy:   policy,
+			attempts: 3,
+			want: buildv1beta1.ImageCondition{
+				Status:   buildv1beta1.ImageConditionStatusFailed,
+				Attempts: 3,
+				Reason:   ReasonRetryLimitExceeded,
+				Message:  "upload failed 3 times: job failed",
+			},
+		},
+		{
+			name:   "superseded",
+			policy: policy,
+			others: []buildv1beta1.ImageCondition{
+				{Type: buildv1beta1.ImageConditionTypeUploaded, Status: buildv1beta1.ImageConditionStatusTrue, Target: "ghcr.io/takutakahashi/test", Revision: "master", ResolvedRevision: "bbb", LastTransitionTime: &later},
+			},
+			want: failed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := newOptionsTestImage()
+			image.Spec.RetryPolicy = tt.policy
+			cond := failed
+			cond.Attempts = tt.attempts
+			image.Status.Conditions = append([]buildv1beta1.ImageCondition{cond}, tt.others...)
+			if wait := RetryUploads(image, now); wait != tt.wait {
+				t.Errorf("RetryUploads() = %s, want %s", wait, tt.wait)
+			}
+			got := image.Status.Conditions[0]
+			if tt.want.Attempts == 0 {
+				tt.want.Attempts = tt.attempts
+			}
+			if got.Status != tt.want.Status || got.Attempts != tt.want.Attempts || got.Reason != tt.want.Reason || got.Message != tt.want.Message {
+				t.Errorf("condition = %s/%d/%s/%q, want %s/%d/%s/%q", got.Status, got.Attempts, got.Reason, got.Message,
+					tt.want.Status, tt.want.Attempts, tt.want.Reason, tt.want.Message)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	image := newOptionsTestImage()
+	image.Spec.RetryPolicy = &buildv1beta1.ImageRetryPolicy{MaxAttempts: 10, Backoff: &metav1.Duration{Duration: 10 * time.Minute}}
+	cond := buildv1beta1.ImageCondition{Type: buildv1beta1.ImageConditionTypeUploaded, Revision: "master"}
+	for attempts, want := range map[int32]time.Duration{1: 10 * time.Minute, 2: 20 * time.Minute, 3: 40 * time.Minute, 4: time.Hour, 9: time.Hour} {
+		if got := retryBackoff(image, cond, attempts); got != want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+	image.Spec.RetryPolicy.Jitter = 50
+	got := retryBackoff(image, cond, 1)
+	if got < 10*time.Minute || got > 15*time.Minute || got != retryBackoff(image, cond, 1) {
+		t.Errorf("retryBackoff() with jitter = %s", got)
+	}
+}
+
+func TestGenName_Attempts(t *testing.T) {
+	cond := buildv1beta1.ImageCondition{Type: buildv1beta1.ImageConditionTypeUploaded, Revision: "master", ResolvedRevision: "aaa"}
+	first := genName("test", cond)
+	cond.Attempts = 1
+	if got := genName("test", cond); got != first {
+		t.Errorf("first attempt has a new name %s, want %s", got, first)
+	}
+	cond.Attempts = 2
+	if got := genName("test", cond); got == first {
+		t.Errorf("retry has the same name %s", got)
+	}
+}
@@ -0,0 +1,66 @@
+package image
+
+import (
+	"encoding/json"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+// defaultPodSecurityContext is compatible with the restricted pod security standard with defaultSecurityContext.
+func defaultPodSecurityContext() *corev1.PodSecurityContext {
+	return &corev1.PodSecurityContext{
+		RunAsNonRoot:   pointer.Bool(true),
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// defaultSecurityContext is the security context of the actor container and containers without their own one.
+// The root filesystem is read-only, and the actor writes to tmpdir.
+func defaultSecurityContext() *corev1.SecurityContext {
+	return &corev1.SecurityContext{
+		AllowPrivilegeEscalation: pointer.Bool(false),
+		ReadOnlyRootFilesystem:   pointer.Bool(true),
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+	}
+}
+
+// podSecurityContext returns the pod security context of the template merged over the default.
+func podSecurityContext(spec *buildv1beta1.ImageFlowTemplateSpecTemplate) (*corev1apply.PodSecurityContextApplyConfiguration, error) {
+	ret := &corev1apply.PodSecurityContextApplyConfiguration{}
+	if err := mergeOverDefault(defaultPodSecurityContext(), spec.PodSecurityContext, ret, corev1.PodSecurityContext{}); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// securityContext returns the security context of the container merged over the default.
+func securityContext(container *corev1apply.ContainerApplyConfiguration) (*corev1apply.SecurityContextApplyConfiguration, error) {
+	ret := &corev1apply.SecurityContextApplyConfiguration{}
+	if err := mergeOverDefault(defaultSecurityContext(), container.SecurityContext, ret, corev1.SecurityContext{}); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// mergeOverDefault merges override over def with strategic merge patch and stores the result to out.
+func mergeOverDefault(def, override, out, dataStruct interface{}) error {
+	original, err := json.Marshal(def)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(override)
+	if err != nil {
+		return err
+	}
+	merged := original
+	if string(patch) != "null" {
+		if merged, err = strategicpatch.StrategicMergePatch(original, patch, dataStruct); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(merged, out)
+}
@@ -0,0 +1,86 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestActorPodSpec_SecurityContext(t *testing.T) {
+	tests := []struct {
+		name           string
+		spec           *buildv1beta1.ImageFlowTemplateSpecTemplate
+		runAsNonRoot   bool
+		readOnlyRootFS bool
+	}{
+		{
+			name: "restricted",
+			spec: &buildv1beta1.ImageFlowTemplateSpecTemplate{
+				Actor:    &buildv1beta1.ContainerApplyConfiguration{},
+				Sidecars: []buildv1beta1.ContainerApplyConfiguration{buildv1beta1.ContainerApplyConfiguration(*corev1apply.Container().WithName("dind"))},
+			},
+			runAsNonRoot:   true,
+			readOnlyRootFS: true,
+		},
+		{
+			name: "relaxed",
+			spec: &buildv1beta1.ImageFlowTemplateSpecTemplate{
+				Actor: (*buildv1beta1.ContainerApplyConfiguration)(corev1apply.Container().
+					WithSecurityContext(corev1apply.SecurityContext().WithReadOnlyRootFilesystem(false))),
+				Sidecars: []buildv1beta1.ContainerApplyConfiguration{buildv1beta1.ContainerApplyConfiguration(*corev1apply.Container().WithName("dind").
+					WithSecurityContext(corev1apply.SecurityContext().WithReadOnlyRootFilesystem(false)))},
+				PodSecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: pointer.Bool(false)},
+			},
+			runAsNonRoot:   false,
+			readOnlyRootFS: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := actorPodSpec(tt.spec, "test-actor", actorContainer("test", "default", tt.spec, "upload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			psc := got.SecurityContext
+			if *psc.RunAsNonRoot != tt.runAsNonRoot || *psc.SeccompProfile.Type != corev1.SeccompProfileTypeRuntimeDefault {
+				t.Errorf("pod security context = %v", psc)
+			}
+			if len(got.Containers) != 2 {
+				t.Fatalf("containers = %v", got.Containers)
+			}
+			if sc := got.Containers[0].SecurityContext; *sc.ReadOnlyRootFilesystem != tt.readOnlyRootFS || *sc.AllowPrivilegeEscalation || sc.Capabilities.Drop[0] != "ALL" {
+				t.Errorf("security context of the actor = %v", sc)
+			}
+			if sc := got.Containers[1].SecurityContext; *sc.ReadOnlyRootFilesystem != tt.readOnlyRootFS {
+				t.Errorf("security context of the sidecar = %v", sc)
+			}
+		})
+	}
+}
+
+func TestActorPodSpec_PrivilegedSidecar(t *testing.T) {
+	spec := &buildv1beta1.ImageFlowTemplateSpecTemplate{
+		Actor:          &buildv1beta1.ContainerApplyConfiguration{},
+		InitContainers: []buildv1beta1.ContainerApplyConfiguration{buildv1beta1.ContainerApplyConfiguration(*corev1apply.Container().WithName("init"))},
+		Sidecars: []buildv1beta1.ContainerApplyConfiguration{buildv1beta1.ContainerApplyConfiguration(*corev1apply.Container().WithName("dind").WithImage("docker:dind").
+			WithSecurityContext(corev1apply.SecurityContext().WithPrivileged(true)))},
+	}
+	got, err := actorPodSpec(spec, "test-actor", actorContainer("test", "default", spec, "upload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc := got.Containers[0].SecurityContext; !*sc.ReadOnlyRootFilesystem || *sc.AllowPrivilegeEscalation || sc.Capabilities.Drop[0] != "ALL" {
+		t.Errorf("security context of the actor = %v", sc)
+	}
+	want := corev1apply.SecurityContext().WithPrivileged(true)
+	if diff := cmp.Diff(want, got.Containers[1].SecurityContext); diff != "" {
+		t.Errorf("security context of the sidecar should be kept: %s", diff)
+	}
+	if sc := got.InitContainers[0].SecurityContext; !*sc.ReadOnlyRootFilesystem || *sc.AllowPrivilegeEscalation || sc.Capabilities.Drop[0] != "ALL" {
+		t.Errorf("security context of the init container = %v", sc)
+	}
+}
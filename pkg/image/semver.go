@@ -0,0 +1,85 @@
+package image
+
+import (
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+)
+
+// AllTags is passed to detect actors in TARGET_TAGS when all tags are needed to resolve semver policies.
+const AllTags = "*"
+
+// SemverTag is a git tag which satisfies a semver tag policy.
+type SemverTag struct {
+	Name             string
+	ResolvedRevision string
+}
+
+// MatchSemver returns tags which satisfy the semver tag policy, from the highest version.
+// Tags which are not semantic versions are ignored.
+// Only the highest version is returned unless the strategy is all.
+func MatchSemver(policy buildv1beta1.ImageTagPolicy, tags map[string]string) ([]SemverTag, error) {
+	opt := buildv1beta1.ImageTagPolicySemver{}
+	if policy.Semver != nil {
+		opt = *policy.Semver
+	}
+	constraint, err := ParseSemverConstraint(opt.Constraint)
+	if err != nil {
+		return nil, err
+	}
+	type version struct {
+		tag SemverTag
+		v   *semver.Version
+	}
+	matched := []version{}
+	for name, rev := range tags {
+		v, err := semver.NewVersion(name)
+		if err != nil || rev == "" {
+			continue
+		}
+		if !matchSemver(constraint, v, opt.IncludePrerelease) {
+			continue
+		}
+		matched = append(matched, version{tag: SemverTag{Name: name, ResolvedRevision: rev}, v: v})
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if c := matched[i].v.Compare(matched[j].v); c != 0 {
+			return c > 0
+		}
+		return matched[i].tag.Name < matched[j].tag.Name
+	})
+	ret := []SemverTag{}
+	for _, m := range matched {
+		ret = append(ret, m.tag)
+		if opt.Strategy != buildv1beta1.ImageSemverStrategyAll {
+			break
+		}
+	}
+	return ret, nil
+}
+
+// ParseSemverConstraint parses the constraint of a semver tag policy.
+// An empty constraint matches all versions.
+func ParseSemverConstraint(constraint string) (*semver.Constraints, error) {
+	if constraint == "" {
+		constraint = "*"
+	}
+	return semver.NewConstraint(constraint)
+}
+
+// matchSemver checks the version against the constraint.
+// A prerelease is included when its release version satisfies the constraint and includePrerelease is set.
+func matchSemver(constraint *semver.Constraints, v *semver.Version, includePrerelease bool) bool {
+	if v.Prerelease() == "" {
+		return constraint.Check(v)
+	}
+	if !includePrerelease {
+		return false
+	}
+	release, err := v.SetPrerelease("")
+	if err != nil {
+		return false
+	}
+	return constraint.Check(&release)
+}
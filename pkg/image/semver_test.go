@@ -0,0 +1,95 @@
+package image
+
+import (
+	"reflect"
+	"testing"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+)
+
+func TestMatchSemver(t *testing.T) {
+	tags := map[string]string{
+		"v1.3.9":       "139",
+		"v1.4.0":       "140",
+		"v1.5.0-rc.1":  "150rc1",
+		"v1.4.1":       "141",
+		"v2.0.0":       "200",
+		"release-test": "release",
+		"latest/hash":  "200",
+	}
+	tests := []struct {
+		name    string
+		policy  buildv1beta1.ImageTagPolicy
+		want    []SemverTag
+		wantErr bool
+	}{
+		{
+			name: "highest",
+			policy: buildv1beta1.ImageTagPolicy{
+				Policy: buildv1beta1.ImageTagPolicyTypeSemver,
+				Semver: &buildv1beta1.ImageTagPolicySemver{Constraint: ">=1.4.0 <2.0.0"},
+			},
+			want: []SemverTag{{Name: "v1.4.1", ResolvedRevision: "141"}},
+		},
+		{
+			name: "all",
+			policy: buildv1beta1.ImageTagPolicy{
+				Policy: buildv1beta1.ImageTagPolicyTypeSemver,
+				Semver: &buildv1beta1.ImageTagPolicySemver{
+					Constraint: ">=1.4.0 <2.0.0",
+					Strategy:   buildv1beta1.ImageSemverStrategyAll,
+				},
+			},
+			want: []SemverTag{
+				{Name: "v1.4.1", ResolvedRevision: "141"},
+				{Name: "v1.4.0", ResolvedRevision: "140"},
+			},
+		},
+		{
+			name: "prerelease",
+			policy: buildv1beta1.ImageTagPolicy{
+				Policy: buildv1beta1.ImageTagPolicyTypeSemver,
+				Semver: &buildv1beta1.ImageTagPolicySemver{
+					Constraint:        ">=1.4.0 <2.0.0",
+					IncludePrerelease: true,
+				},
+			},
+			want: []SemverTag{{Name: "v1.5.0-rc.1", ResolvedRevision: "150rc1"}},
+		},
+		{
+			name: "no_constraint",
+			policy: buildv1beta1.ImageTagPolicy{
+				Policy: buildv1beta1.ImageTagPolicyTypeSemver,
+			},
+			want: []SemverTag{{Name: "v2.0.0", ResolvedRevision: "200"}},
+		},
+		{
+			name: "no_match",
+			policy: buildv1beta1.ImageTagPolicy{
+				Policy: buildv1beta1.ImageTagPolicyTypeSemver,
+				Semver: &buildv1beta1.ImageTagPolicySemver{Constraint: ">=3.0.0"},
+			},
+			want: []SemverTag{},
+		},
+		{
+			name: "invalid_constraint",
+			policy: buildv1beta1.ImageTagPolicy{
+				Policy: buildv1beta1.ImageTagPolicyTypeSemver,
+				Semver: &buildv1beta1.ImageTagPolicySemver{Constraint: "invalid"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchSemver(tt.policy, tags)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MatchSemver() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchSemver() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
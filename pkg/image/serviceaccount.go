@@ -0,0 +1,136 @@
+package image
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+	rbacv1apply "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// serviceAccountName returns the service account of the actors of the image.
+// Generated names contain the namespace of the image when actors of many namespaces run in the same namespace.
+func serviceAccountName(image *buildv1beta1.Image, opts Options) string {
+	if image.Spec.ServiceAccountName != "" {
+		return image.Spec.ServiceAccountName
+	}
+	if opts.actorNamespace(image) == image.Namespace {
+		return fmt.Sprintf("%s-actor", image.Name)
+	}
+	return fmt.Sprintf("%s-%s-actor", image.Namespace, image.Name)
+}
+
+// EnsureServiceAccount creates the service account of actors and the role to get the image and update its status.
+// The role and the role binding are created in the namespace of the image, and the service account is created in the namespace of actors.
+// They are deleted when the image is being deleted or it names its own service account.
+func EnsureServiceAccount(ctx context.Context, c client.Client, image *buildv1beta1.Image, opts Options) error {
+	if image.DeletionTimestamp != nil || image.Spec.ServiceAccountName != "" {
+		return deleteServiceAccount(ctx, c, image, opts)
+	}
+	name := serviceAccountName(image, opts)
+	namespace := opts.actorNamespace(image)
+	labels := setLabel(image.Name, nil)
+	sa := corev1apply.ServiceAccount(name, namespace).
+		WithLabels(labels).
+		WithOwnerReferences(opts.ownerReferences(image)...)
+	if err := applyObject(ctx, c, sa, &corev1.ServiceAccount{}, func(obj client.Object) (interface{}, error) {
+		return corev1apply.ExtractServiceAccount(obj.(*corev1.ServiceAccount), "image-controller")
+	}); err != nil {
+		return errors.Wrap(err, "failed to apply service account")
+	}
+	// the role is in the namespace of the image, so it can be always owned by the image
+	owner := Options{InImageNamespace: true}.ownerReferences(image)
+	role := rbacv1apply.Role(name, image.Namespace).
+		WithLabels(labels).
+		WithOwnerReferences(owner...).
+		WithRules(
+			rbacv1apply.PolicyRule().
+				WithAPIGroups(buildv1beta1.GroupVersion.Group).
+				WithResources("images").
+				WithResourceNames(image.Name).
+				WithVerbs("get"),
+			rbacv1apply.PolicyRule().
+				WithAPIGroups(buildv1beta1.GroupVersion.Group).
+				WithResources("images/status").
+				WithResourceNames(image.Name).
+				WithVerbs("update"),
+		)
+	if err := applyObject(ctx, c, role, &rbacv1.Role{}, func(obj client.Object) (interface{}, error) {
+		return rbacv1apply.ExtractRole(obj.(*rbacv1.Role), "image-controller")
+	}); err != nil {
+		return errors.Wrap(err, "failed to apply role")
+	}
+	binding := rbacv1apply.RoleBinding(name, image.Namespace).
+		WithLabels(labels).
+		WithOwnerReferences(owner...).
+		WithRoleRef(rbacv1apply.RoleRef().WithAPIGroup(rbacv1.GroupName).WithKind("Role").WithName(name)).
+		WithSubjects(rbacv1apply.Subject().WithKind(rbacv1.ServiceAccountKind).WithName(name).WithNamespace(namespace))
+	if err := applyObject(ctx, c, binding, &rbacv1.RoleBinding{}, func(obj client.Object) (interface{}, error) {
+		return rbacv1apply.ExtractRoleBinding(obj.(*rbacv1.RoleBinding), "image-controller")
+	}); err != nil {
+		return errors.Wrap(err, "failed to apply role binding")
+	}
+	return nil
+}
+
+// deleteServiceAccount deletes the service account, the role and the role binding which were created for the image.
+func deleteServiceAccount(ctx context.Context, c client.Client, image *buildv1beta1.Image, opts Options) error {
+	generated := *image.DeepCopy()
+	generated.Spec.ServiceAccountName = ""
+	name := serviceAccountName(&generated, opts)
+	objs := []client.Object{
+		&rbacv1.RoleBinding{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: image.Namespace}},
+		&rbacv1.Role{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: image.Namespace}},
+		&corev1.ServiceAccount{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: opts.actorNamespace(image)}},
+	}
+	for _, obj := range objs {
+		if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		// objects with the same name which are not created by the controller are kept
+		if obj.GetLabels()["build.takutakahashi.dev/image"] != image.Name {
+			continue
+		}
+		if err := c.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyObject applies the apply configuration unless the fields managed by the controller are the same as current.
+// extract returns the apply configuration of current which is fetched into the empty object.
+func applyObject(ctx context.Context, c client.Client, config interface{}, current client.Object, extract func(client.Object) (interface{}, error)) error {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(config)
+	if err != nil {
+		return err
+	}
+	patch := &unstructured.Unstructured{Object: obj}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(patch), current); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	currApplyConfig, err := extract(current)
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(config, currApplyConfig) {
+		return nil
+	}
+	return c.Patch(ctx, patch, client.Apply, &client.PatchOptions{
+		FieldManager: "image-controller",
+		Force:        pointer.Bool(true),
+	})
+}
@@ -0,0 +1,96 @@
+package image
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestServiceAccountName(t *testing.T) {
+	tests := []struct {
+		name    string
+		account string
+		opts    Options
+		want    string
+	}{
+		{
+			name: "actor_namespace",
+			opts: Options{},
+			want: "default-test-actor",
+		},
+		{
+			name: "image_namespace",
+			opts: Options{InImageNamespace: true},
+			want: "test-actor",
+		},
+		{
+			name:    "spec",
+			account: "builder",
+			opts:    Options{InImageNamespace: true},
+			want:    "builder",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := newOptionsTestImage()
+			image.Spec.ServiceAccountName = tt.account
+			if got := serviceAccountName(image, tt.opts); got != tt.want {
+				t.Errorf("serviceAccountName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureServiceAccount_Delete(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	image := newOptionsTestImage()
+	image.Spec.ServiceAccountName = "builder"
+	labels := setLabel(image.Name, nil)
+	objs := []client.Object{
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test-actor", Namespace: "default", Labels: labels}},
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "test-actor", Namespace: "default", Labels: labels}},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "test-actor", Namespace: "default", Labels: labels}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "builder", Namespace: "default"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	if err := EnsureServiceAccount(context.Background(), c, image, Options{InImageNamespace: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objs[:3] {
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(obj), obj); !apierrors.IsNotFound(err) {
+			t.Errorf("%T %s is not deleted: %v", obj, obj.GetName(), err)
+		}
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(objs[3]), objs[3]); err != nil {
+		t.Errorf("service account in spec is deleted: %v", err)
+	}
+}
+
+func TestEnsureServiceAccount_KeepUnmanaged(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	image := newOptionsTestImage()
+	now := metav1.Now()
+	image.DeletionTimestamp = &now
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default-test-actor", Namespace: DefaultActorNamespace}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sa).Build()
+	if err := EnsureServiceAccount(context.Background(), c, image, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(sa), sa); err != nil {
+		t.Errorf("unmanaged service account is deleted: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package image
+
+import (
+	"sort"
+	"strings"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+)
+
+type latestKey struct {
+	target    string
+	tagPolicy buildv1beta1.ImageTagPolicyType
+	revision  string
+}
+
+type latestState struct {
+	status   buildv1beta1.ImageLatestStatus
+	checked  *buildv1beta1.ImageCondition
+	uploaded *buildv1beta1.ImageCondition
+}
+
+// UpdateSummary fills ObservedGeneration, Phase, Latest and LatestTags of the status from conditions.
+func UpdateSummary(image *buildv1beta1.Image) {
+	image.Status.ObservedGeneration = image.Generation
+	image.Status.Latest = Latest(image.Status.Conditions)
+	image.Status.LatestTags = LatestTags(image.Status.Latest)
+	image.Status.Phase = Phase(image.Status.Conditions)
+}
+
+// Latest summarizes conditions into the latest detected and uploaded revision of each tag policy, revision and target.
+func Latest(conditions []buildv1beta1.ImageCondition) []buildv1beta1.ImageLatestStatus {
+	states := map[latestKey]*latestState{}
+	keys := []latestKey{}
+	for i := range conditions {
+		cond := &conditions[i]
+		if !cond.Type.IsRevision() {
+			continue
+		}
+		key := latestKey{target: cond.Target, tagPolicy: cond.TagPolicy, revision: cond.Revision}
+		state, ok := states[key]
+		if !ok {
+			state = &latestState{status: buildv1beta1.ImageLatestStatus{Target: key.target, TagPolicy: key.tagPolicy, Revision: key.revision}}
+			states[key] = state
+			keys = append(keys, key)
+		}
+		switch cond.Type {
+		case buildv1beta1.ImageConditionTypeChecked:
+			if newer(cond, state.checked) {
+				state.checked = cond
+			}
+		case buildv1beta1.ImageConditionTypeUploaded:
+			if cond.Status == buildv1beta1.ImageConditionStatusTrue && newer(cond, state.uploaded) {
+				state.uploaded = cond
+			}
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].target != keys[j].target {
+			return keys[i].target < keys[j].target
+		}
+		if keys[i].tagPolicy != keys[j].tagPolicy {
+			return keys[i].tagPolicy < keys[j].tagPolicy
+		}
+		return keys[i].revision < keys[j].revision
+	})
+	ret := []buildv1beta1.ImageLatestStatus{}
+	for _, key := range keys {
+		state := states[key]
+		if state.checked != nil {
+			state.status.ResolvedRevision = state.checked.ResolvedRevision
+			state.status.LastTransitionTime = state.checked.LastTransitionTime
+		}
+		if state.uploaded != nil {
+			state.status.UploadedRevision = state.uploaded.ResolvedRevision
+			state.status.UploadedTag = ImageTag(*state.uploaded)
+			state.status.UploadedDigest = state.uploaded.Digest
+			if newer(state.uploaded, state.checked) {
+				state.status.LastTransitionTime = state.uploaded.LastTransitionTime
+			}
+		}
+		if state.checked == nil && state.uploaded == nil {
+			continue
+		}
+		ret = append(ret, state.status)
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// LatestTags joins the uploaded tags of latest with commas in the order of latest, without duplicates.
+// Tags are shared by targets, so each tag appears once.
+func LatestTags(latest []buildv1beta1.ImageLatestStatus) string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, l := range latest {
+		if l.UploadedTag == "" || seen[l.UploadedTag] {
+			continue
+		}
+		seen[l.UploadedTag] = true
+		tags = append(tags, l.UploadedTag)
+	}
+	return strings.Join(tags, ",")
+}
+
+// Phase summarizes conditions into a phase.
+// It is Failed when the template is not ready or the Image can't be reconciled.
+// Failed uploads are reported only while no later upload of the same revision succeeded.
+// Failed checks are reported until the revision moves.
+func Phase(conditions []buildv1beta1.ImageCondition) buildv1beta1.ImagePhase {
+	if len(conditions) == 0 {
+		return buildv1beta1.ImagePhasePending
+	}
+	checking, uploading, failed, uploaded := false, false, false, false
+	latestUploaded := map[latestKey]*buildv1beta1.ImageCondition{}
+	for i := range conditions {
+		cond := &conditions[i]
+		switch {
+		case cond.Type == buildv1beta1.ImageConditionTypeTemplateReady && cond.Status == buildv1beta1.ImageConditionStatusFalse,
+			cond.Type == buildv1beta1.ImageConditionTypeReconciled && cond.Status == buildv1beta1.ImageConditionStatusFalse:
+			return buildv1beta1.ImagePhaseFailed
+		case cond.Type == buildv1beta1.ImageConditionTypeChecked && cond.Status == buildv1beta1.ImageConditionStatusFalse:
+			checking = true
+		case cond.Type == buildv1beta1.ImageConditionTypeChecked && cond.Status == buildv1beta1.ImageConditionStatusFailed:
+			// checked conditions are replaced when the revision moves, so a failed one is the latest
+			failed = true
+		case cond.Type == buildv1beta1.ImageConditionTypeUploaded && cond.Status == buildv1beta1.ImageConditionStatusFalse:
+			uploading = true
+		case cond.Type == buildv1beta1.ImageConditionTypeUploaded &&
+			(cond.Status == buildv1beta1.ImageConditionStatusTrue || cond.Status == buildv1beta1.ImageConditionStatusFailed):
+			key := latestKey{target: cond.Target, tagPolicy: cond.TagPolicy, revision: cond.Revision}
+			if newer(cond, latestUploaded[key]) {
+				latestUploaded[key] = cond
+			}
+		}
+	}
+	for _, cond := range latestUploaded {
+		if cond.Status == buildv1beta1.ImageConditionStatusFailed {
+			failed = true
+		} else {
+			uploaded = true
+		}
+	}
+	switch {
+	case failed:
+		return buildv1beta1.ImagePhaseFailed
+	case uploading:
+		return buildv1beta1.ImagePhaseBuilding
+	case checking:
+		return buildv1beta1.ImagePhaseDetecting
+	case uploaded:
+		return buildv1beta1.ImagePhaseReady
+	}
+	return buildv1beta1.ImagePhasePending
+}
+
+// newer returns true if a transitioned later than b. conditions without time are treated as the oldest.
+func newer(a, b *buildv1beta1.ImageCondition) bool {
+	if b == nil {
+		return true
+	}
+	if a.LastTransitionTime == nil {
+		return b.LastTransitionTime == nil
+	}
+	if b.LastTransitionTime == nil {
+		return true
+	}
+	return !a.LastTransitionTime.Before(b.LastTransitionTime)
+}
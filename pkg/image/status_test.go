@@ -0,0 +1,145 @@
+package image
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLatest(t *testing.T) {
+	t1 := v1.NewTime(time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC))
+	t2 := v1.NewTime(time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name       string
+		conditions []buildv1beta1.ImageCondition
+		want       []buildv1beta1.ImageLatestStatus
+	}{
+		{
+			name:       "empty",
+			conditions: []buildv1beta1.ImageCondition{},
+			want:       nil,
+		},
+		{
+			name: "checked_and_uploaded",
+			conditions: []buildv1beta1.ImageCondition{
+				{Type: buildv1beta1.ImageConditionTypeChecked, Status: buildv1beta1.ImageConditionStatusTrue, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchHash, Target: "b", Revision: "master", ResolvedRevision: "bbb", LastTransitionTime: &t2},
+				{Type: buildv1beta1.ImageConditionTypeUploaded, Status: buildv1beta1.ImageConditionStatusTrue, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchHash, Target: "b", Revision: "master", ResolvedRevision: "aaa", Digest: "sha256:aaa", LastTransitionTime: &t1},
+				{Type: buildv1beta1.ImageConditionTypeUploaded, Status: buildv1beta1.ImageConditionStatusFalse, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchHash, Target: "b", Revision: "master", ResolvedRevision: "bbb", LastTransitionTime: &t2},
+				{Type: buildv1beta1.ImageConditionTypeChecked, Status: buildv1beta1.ImageConditionStatusFalse, TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Target: "a", Revision: "main", ResolvedRevision: "ccc", LastTransitionTime: &t1},
+			},
+			want: []buildv1beta1.ImageLatestStatus{
+				{Target: "a", TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "main", ResolvedRevision: "ccc", LastTransitionTime: &t1},
+				{Target: "b", TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchHash, Revision: "master", ResolvedRevision: "bbb", UploadedRevision: "aaa", UploadedTag: "aaa", UploadedDigest: "sha256:aaa", LastTransitionTime: &t2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, Latest(tt.conditions)); diff != "" {
+				t.Errorf("Latest() diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestLatestTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		latest []buildv1beta1.ImageLatestStatus
+		want   string
+	}{
+		{
+			name:   "empty",
+			latest: nil,
+			want:   "",
+		},
+		{
+			name: "policies_and_targets",
+			latest: []buildv1beta1.ImageLatestStatus{
+				{Target: "a", TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "main", UploadedTag: "main"},
+				{Target: "a", TagPolicy: buildv1beta1.ImageTagPolicyTypeSemver, Revision: "v1.2.0", UploadedTag: "v1.2.0"},
+				{Target: "a", TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "release", ResolvedRevision: "ccc"},
+				{Target: "b", TagPolicy: buildv1beta1.ImageTagPolicyTypeBranchName, Revision: "main", UploadedTag: "main"},
+			},
+			want: "main,v1.2.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LatestTags(tt.latest); got != tt.want {
+				t.Errorf("LatestTags() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhase(t *testing.T) {
+	t1 := v1.NewTime(time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC))
+	t2 := v1.NewTime(time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name       string
+		conditions []buildv1beta1.ImageCondition
+		want       buildv1beta1.ImagePhase
+	}{
+		{
+			name: "pending",
+			want: buildv1beta1.ImagePhasePending,
+		},
+		{
+			name: "detecting",
+			conditions: []buildv1beta1.ImageCondition{
+				{Type: buildv1beta1.ImageConditionTypeChecked, Status: buildv1beta1.ImageConditionStatusFalse, Revision: "master"},
+			},
+			want: buildv1beta1.ImagePhaseDetecting,
+		},
+		{
+			name: "building",
+			conditions: []buildv1beta1.ImageCondition{
+				{Type: buildv1beta1.ImageConditionTypeChecked, Status: buildv1beta1.ImageConditionStatusTrue, Revision: "master"},
+				{Type: buildv1beta1.ImageConditionTypeUploaded, Status: buildv1beta1.ImageConditionStatusFalse, Revision: "master"},
+			},
+			want: buildv1beta1.ImagePhaseBuilding,
+		},
+		{
+			name: "failed",
+			conditions: []buildv1beta1.ImageCondition{
+				{Type: buildv1beta1.ImageConditionTypeUploaded, Status: buildv1beta1.ImageConditionStatusTrue, Revision: "master", LastTransitionTime: &t1},
+				{Type: buildv1beta1.ImageConditionTypeUploaded, Status: buildv1beta1.ImageConditionStatusFailed, Revision: "master", LastTransitionTime: &t2},
+			},
+			want: buildv1beta1.ImagePhaseFailed,
+		},
+		{
+			name: "check_failed",
+			conditions: []buildv1beta1.ImageCondition{
+				{Type: buildv1beta1.ImageConditionTypeChecked, Status: buildv1beta1.ImageConditionStatusFailed, Revision: "master"},
+			},
+			want: buildv1beta1.ImagePhaseFailed,
+		},
+		{
+			name: "not_reconciled",
+			conditions: []buildv1beta1.ImageCondition{
+				{Type: buildv1beta1.ImageConditionTypeUploaded, Status: buildv1beta1.ImageConditionStatusTrue, Revision: "master"},
+				{Type: buildv1beta1.ImageConditionTypeReconciled, Status: buildv1beta1.ImageConditionStatusFalse, Reason: ReasonSecretNotFound},
+			},
+			want: buildv1beta1.ImagePhaseFailed,
+		},
+		{
+			name: "recovered",
+			conditions: []buildv1beta1.ImageCondition{
+				{Type: buildv1beta1.ImageConditionTypeUploaded, Status: buildv1beta1.ImageConditionStatusFailed, Revision: "master", LastTransitionTime: &t1},
+				{Type: buildv1beta1.ImageConditionTypeUploaded, Status: buildv1beta1.ImageConditionStatusTrue, Revision: "master", LastTransitionTime: &t2},
+			},
+			want: buildv1beta1.ImagePhaseReady,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Phase(tt.conditions); got != tt.want {
+				t.Errorf("Phase() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
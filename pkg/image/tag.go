@@ -0,0 +1,66 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+)
+
+// TagTemplateData is passed to TagTemplate.
+type TagTemplateData struct {
+	// Revision is the branch or tag name of the condition.
+	Revision string
+	// Branch is set when the tag policy is for branches.
+	Branch string
+	// GitTag is set when the tag policy is for tags.
+	GitTag    string
+	SHA       string
+	ShortSHA  string
+	Date      string
+	Timestamp string
+}
+
+// TagTemplate returns the tag template of the target, or the one of the repository when the target has no template.
+func TagTemplate(repository buildv1beta1.ImageRepository, target buildv1beta1.ImageTarget) string {
+	if target.TagTemplate != "" {
+		return target.TagTemplate
+	}
+	return repository.TagTemplate
+}
+
+// RenderTag renders the tag template for the condition.
+// The result is sanitized to a valid image tag.
+func RenderTag(tmpl string, cond buildv1beta1.ImageCondition, now time.Time) (string, error) {
+	t, err := template.New("tag").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	data := TagTemplateData{
+		Revision:  cond.Revision,
+		SHA:       cond.ResolvedRevision,
+		ShortSHA:  cond.ResolvedRevision,
+		Date:      now.UTC().Format("20060102"),
+		Timestamp: now.UTC().Format("20060102150405"),
+	}
+	if len(data.ShortSHA) > 7 {
+		data.ShortSHA = data.ShortSHA[:7]
+	}
+	switch cond.TagPolicy {
+	case buildv1beta1.ImageTagPolicyTypeBranchHash, buildv1beta1.ImageTagPolicyTypeBranchName:
+		data.Branch = cond.Revision
+	case buildv1beta1.ImageTagPolicyTypeTagHash, buildv1beta1.ImageTagPolicyTypeTagName, buildv1beta1.ImageTagPolicyTypeSemver:
+		data.GitTag = cond.Revision
+	}
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, data); err != nil {
+		return "", err
+	}
+	tag := sanitizeTag(buf.String())
+	if tag == "" {
+		return "", fmt.Errorf("tag rendered from %q is empty", tmpl)
+	}
+	return tag, nil
+}
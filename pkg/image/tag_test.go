@@ -0,0 +1,68 @@
+package image
+
+import (
+	"testing"
+	"time"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+)
+
+func TestRenderTag(t *testing.T) {
+	now := time.Date(2022, 5, 2, 10, 20, 30, 0, time.UTC)
+	tests := []struct {
+		name    string
+		tmpl    string
+		cond    buildv1beta1.ImageCondition
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "branch",
+			tmpl: "{{ .Branch }}-{{ .ShortSHA }}-{{ .Date }}",
+			cond: buildv1beta1.ImageCondition{
+				TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+				Revision:         "feature/test",
+				ResolvedRevision: "0123456789abcdef",
+			},
+			want: "feature-test-0123456-20220502",
+		},
+		{
+			name: "git_tag",
+			tmpl: "{{ .GitTag }}-{{ .Timestamp }}",
+			cond: buildv1beta1.ImageCondition{
+				TagPolicy:        buildv1beta1.ImageTagPolicyTypeSemver,
+				Revision:         "v1.2.3",
+				ResolvedRevision: "0123456789abcdef",
+			},
+			want: "v1.2.3-20220502102030",
+		},
+		{
+			name: "empty",
+			tmpl: "{{ .GitTag }}",
+			cond: buildv1beta1.ImageCondition{
+				TagPolicy:        buildv1beta1.ImageTagPolicyTypeBranchHash,
+				Revision:         "main",
+				ResolvedRevision: "0123456789abcdef",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown_field",
+			tmpl:    "{{ .Unknown }}",
+			cond:    buildv1beta1.ImageCondition{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTag(tt.tmpl, tt.cond, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RenderTag() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("RenderTag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
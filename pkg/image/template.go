@@ -0,0 +1,125 @@
+package image
+
+import (
+	"fmt"
+	"strings"
+
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ReasonTemplateValid    = "Valid"
+	ReasonTemplateNotFound = "TemplateNotFound"
+	ReasonTemplateInvalid  = "TemplateInvalid"
+	ReasonRequiredEnv      = "RequiredEnvMissing"
+)
+
+// TemplateError is returned when the template can not run the Image.
+type TemplateError struct {
+	Reason  string
+	Message string
+}
+
+func (e *TemplateError) Error() string {
+	return e.Message
+}
+
+// MissingRequiredEnv returns RequiredEnv which is set neither in the actor, the Image nor the auth env, formatted as phase/name.
+func MissingRequiredEnv(image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate) []string {
+	env := map[string]bool{}
+	for _, e := range image.Spec.Env {
+		env[e.Name] = true
+	}
+	ret := []string{}
+	for _, phase := range []struct {
+		name     buildv1beta1.ImageFlowPhase
+		template buildv1beta1.ImageFlowTemplateSpecTemplate
+	}{
+		{buildv1beta1.ImageFlowPhaseDetect, template.Spec.Detect},
+		{buildv1beta1.ImageFlowPhaseCheck, template.Spec.Check},
+		{buildv1beta1.ImageFlowPhaseUpload, template.Spec.Upload},
+	} {
+		actorEnv := map[string]bool{}
+		if phase.template.Actor != nil {
+			for _, e := range phase.template.Actor.Env {
+				if e.Name != nil {
+					actorEnv[*e.Name] = true
+				}
+			}
+		}
+		for _, name := range authEnvNames(image, phase.name, &phase.template) {
+			actorEnv[name] = true
+		}
+		for _, name := range phase.template.RequiredEnv {
+			if !env[name] && !actorEnv[name] {
+				ret = append(ret, fmt.Sprintf("%s/%s", phase.name, name))
+			}
+		}
+	}
+	return ret
+}
+
+// ValidateTemplate returns a TemplateError if the resolved template can not run the Image.
+func ValidateTemplate(image *buildv1beta1.Image, template *buildv1beta1.ImageFlowTemplate) error {
+	if errs := template.ValidateResolvedSpec(); len(errs) != 0 {
+		return &TemplateError{Reason: ReasonTemplateInvalid, Message: fmt.Sprintf("template %s is invalid: %s", template.Name, errs.ToAggregate())}
+	}
+	if missing := MissingRequiredEnv(image, template); len(missing) != 0 {
+		return &TemplateError{Reason: ReasonRequiredEnv, Message: fmt.Sprintf("required env is not set: %s", strings.Join(missing, ", "))}
+	}
+	return nil
+}
+
+// UpdateTemplateCondition sets TemplateReady condition from the result of ValidateTemplate.
+func UpdateTemplateCondition(conditions []buildv1beta1.ImageCondition, err error) []buildv1beta1.ImageCondition {
+	cond := buildv1beta1.ImageCondition{
+		Type:   buildv1beta1.ImageConditionTypeTemplateReady,
+		Status: buildv1beta1.ImageConditionStatusTrue,
+		Reason: ReasonTemplateValid,
+	}
+	if err != nil {
+		cond.Status = buildv1beta1.ImageConditionStatusFalse
+		cond.Reason = ReasonTemplateInvalid
+		cond.Message = err.Error()
+		if terr, ok := err.(*TemplateError); ok {
+			cond.Reason = terr.Reason
+		}
+	}
+	return SetStatusCondition(conditions, cond)
+}
+
+// SetStatusCondition sets a condition which is not a revision. LastTransitionTime is updated only when the status changes.
+// A new condition is inserted before revisions so that the order survives conversion to v1.
+func SetStatusCondition(conditions []buildv1beta1.ImageCondition, condition buildv1beta1.ImageCondition) []buildv1beta1.ImageCondition {
+	now := v1.Now()
+	for i, c := range conditions {
+		if c.Type != condition.Type {
+			continue
+		}
+		if c.Status == condition.Status && c.LastTransitionTime != nil {
+			condition.LastTransitionTime = c.LastTransitionTime
+		} else {
+			condition.LastTransitionTime = &now
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	condition.LastTransitionTime = &now
+	for i, c := range conditions {
+		if c.Type.IsRevision() {
+			return append(conditions[:i], append([]buildv1beta1.ImageCondition{condition}, conditions[i:]...)...)
+		}
+	}
+	return append(conditions, condition)
+}
+
+// GetStatusCondition returns the condition of the type which is not a revision.
+func GetStatusCondition(conditions []buildv1beta1.ImageCondition, condType buildv1beta1.ImageConditionType) (buildv1beta1.ImageCondition, bool) {
+	for _, c := range conditions {
+		if c.Type == condType {
+			return c, true
+		}
+	}
+	return buildv1beta1.ImageCondition{}, false
+}
@@ -0,0 +1,140 @@
+package image
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	buildv1beta1 "github.com/takutakahashi/oci-image-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	corev1apply "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+func newTestTemplate() *buildv1beta1.ImageFlowTemplate {
+	actor := func(env ...string) *buildv1beta1.ContainerApplyConfiguration {
+		c := &buildv1beta1.ContainerApplyConfiguration{Image: pointer.String("actor")}
+		for _, e := range env {
+			c.Env = append(c.Env, *corev1apply.EnvVar().WithName(e).WithValue("value"))
+		}
+		return c
+	}
+	return &buildv1beta1.ImageFlowTemplate{
+		Spec: buildv1beta1.ImageFlowTemplateSpec{
+			Detect: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor("GITHUB_ORG"), RequiredEnv: []string{"GITHUB_ORG", "GITHUB_TOKEN"}},
+			Check:  buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor()},
+			Upload: buildv1beta1.ImageFlowTemplateSpecTemplate{Actor: actor(), RequiredEnv: []string{"GITHUB_TOKEN"}},
+		},
+	}
+}
+
+func TestMissingRequiredEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     []corev1.EnvVar
+		auth    buildv1beta1.ImageAuth
+		authEnv []buildv1beta1.ImageFlowTemplateAuthEnv
+		want    []string
+	}{
+		{
+			name: "missing",
+			want: []string{"detect/GITHUB_TOKEN", "upload/GITHUB_TOKEN"},
+		},
+		{
+			name: "image_env",
+			env:  []corev1.EnvVar{{Name: "GITHUB_TOKEN", Value: "token"}},
+			want: []string{},
+		},
+		{
+			name:    "auth_env",
+			auth:    buildv1beta1.ImageAuth{Type: buildv1beta1.ImageAuthTypeBasic, SecretName: "github"},
+			authEnv: []buildv1beta1.ImageFlowTemplateAuthEnv{{Name: "GITHUB_TOKEN", Key: "password"}},
+			want:    []string{"upload/GITHUB_TOKEN"},
+		},
+		{
+			name:    "auth_env_without_secret",
+			authEnv: []buildv1beta1.ImageFlowTemplateAuthEnv{{Name: "GITHUB_TOKEN", Key: "password"}},
+			want:    []string{"detect/GITHUB_TOKEN", "upload/GITHUB_TOKEN"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := &buildv1beta1.Image{Spec: buildv1beta1.ImageSpec{Env: tt.env, Repository: buildv1beta1.ImageRepository{Auth: tt.auth}}}
+			imt := newTestTemplate()
+			imt.Spec.Detect.AuthEnv = tt.authEnv
+			if diff := cmp.Diff(tt.want, MissingRequiredEnv(image, imt)); diff != "" {
+				t.Errorf("MissingRequiredEnv() diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*buildv1beta1.ImageFlowTemplate)
+		reason string
+	}{
+		{
+			name:   "valid",
+			modify: func(imt *buildv1beta1.ImageFlowTemplate) {},
+		},
+		{
+			name:   "no_actor",
+			modify: func(imt *buildv1beta1.ImageFlowTemplate) { imt.Spec.Check.Actor = nil },
+			reason: ReasonTemplateInvalid,
+		},
+		{
+			name:   "missing_env",
+			modify: func(imt *buildv1beta1.ImageFlowTemplate) { imt.Spec.Check.RequiredEnv = []string{"REGISTRY_TOKEN"} },
+			reason: ReasonRequiredEnv,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := &buildv1beta1.Image{Spec: buildv1beta1.ImageSpec{Env: []corev1.EnvVar{{Name: "GITHUB_TOKEN", Value: "token"}}}}
+			imt := newTestTemplate()
+			tt.modify(imt)
+			err := ValidateTemplate(image, imt)
+			if tt.reason == "" {
+				if err != nil {
+					t.Errorf("ValidateTemplate() error = %v", err)
+				}
+				return
+			}
+			var terr *TemplateError
+			if !errors.As(err, &terr) || terr.Reason != tt.reason {
+				t.Errorf("ValidateTemplate() error = %v, want reason %s", err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestUpdateTemplateCondition(t *testing.T) {
+	conditions := []buildv1beta1.ImageCondition{
+		{Type: buildv1beta1.ImageConditionTypeChecked, Status: buildv1beta1.ImageConditionStatusTrue, Revision: "master"},
+	}
+	conditions = UpdateTemplateCondition(conditions, &TemplateError{Reason: ReasonRequiredEnv, Message: "required env is not set"})
+	cond, ok := GetStatusCondition(conditions, buildv1beta1.ImageConditionTypeTemplateReady)
+	if !ok || cond.Status != buildv1beta1.ImageConditionStatusFalse || cond.Reason != ReasonRequiredEnv || cond.LastTransitionTime == nil {
+		t.Fatalf("condition = %v", cond)
+	}
+	transitioned := cond.LastTransitionTime
+	conditions = UpdateTemplateCondition(conditions, &TemplateError{Reason: ReasonRequiredEnv, Message: "required env is not set"})
+	if cond, _ := GetStatusCondition(conditions, buildv1beta1.ImageConditionTypeTemplateReady); cond.LastTransitionTime != transitioned {
+		t.Errorf("LastTransitionTime should not be updated without transition")
+	}
+	if conditions[0].Type != buildv1beta1.ImageConditionTypeTemplateReady {
+		t.Errorf("condition should be set before revisions: %v", conditions)
+	}
+	conditions = UpdateTemplateCondition(conditions, nil)
+	if len(conditions) != 2 {
+		t.Fatalf("conditions = %v", conditions)
+	}
+	if cond, _ := GetStatusCondition(conditions, buildv1beta1.ImageConditionTypeTemplateReady); cond.Status != buildv1beta1.ImageConditionStatusTrue || cond.Message != "" {
+		t.Errorf("condition = %v", cond)
+	}
+	if Phase(UpdateTemplateCondition(conditions, errors.New("invalid"))) != buildv1beta1.ImagePhaseFailed {
+		t.Errorf("phase should be failed when the template is not ready")
+	}
+}